@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiHandler serves a JSON control API so that things other than the Tcl GUI
+// (Stream Deck / Companion buttons, scripts, a second operator) can drive
+// the overlay:
+//
+//	GET   /api/scoreboard                 current scoreboard
+//	PUT   /api/scoreboard                 replace the whole scoreboard
+//	PATCH /api/scoreboard                 update only the given fields
+//	POST  /api/scoreboard/increment       ?player=1|2&amount=N (default 1)
+//	POST  /api/scoreboard/reset-scores
+//	POST  /api/scoreboard/swap
+//	POST  /api/scoreboard/undo            back to the previously applied one
+//	POST  /api/scoreboard/redo
+//
+// Every scoreboard endpoint responds with the resulting scoreboard.
+// There are also read-only endpoints for suggestions:
+//
+//	GET   /api/players                    ?q=name (optional)
+//	GET   /api/characters
+//	GET   /api/stages
+//
+// And finished sets from the history log:
+//
+//	GET   /api/history/sets               ?date=YYYY-MM-DD (default today)
+//	                                      &format=json|csv (default json)
+//
+// If token isn't empty, every request must have it, see protectAPI.
+func apiHandler(state *State, catalog *Catalog, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/scoreboard", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, state.Scoreboard())
+
+		case http.MethodPut:
+			var scoreboard Scoreboard
+			if err := decodeJSON(r, &scoreboard); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			updateScoreboard(w, state, func(sb *Scoreboard) error {
+				*sb = scoreboard
+				return nil
+			})
+
+		case http.MethodPatch:
+			// The body is read before taking the state's lock, so that a
+			// slow client can't hold up everyone else.
+			body, err := readJSON(r)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			// Decoding on top of the current scoreboard only overwrites
+			// fields that are present in the request body.
+			updateScoreboard(w, state, func(sb *Scoreboard) error {
+				return decodeRawJSON(body, sb)
+			})
+
+		default:
+			methodNotAllowed(w, "GET, PUT, PATCH")
+		}
+	})
+
+	mux.HandleFunc("/api/scoreboard/increment", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, "POST")
+			return
+		}
+		updateScoreboard(w, state, func(sb *Scoreboard) error {
+			amount := 1
+			if a := r.URL.Query().Get("amount"); a != "" {
+				var err error
+				amount, err = strconv.Atoi(a)
+				if err != nil {
+					return fmt.Errorf("invalid amount: %q", a)
+				}
+			}
+			return sb.IncrementScore(r.URL.Query().Get("player"), amount)
+		})
+	})
+
+	mux.HandleFunc("/api/scoreboard/reset-scores", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, "POST")
+			return
+		}
+		updateScoreboard(w, state, func(sb *Scoreboard) error {
+			sb.ResetScores()
+			return nil
+		})
+	})
+
+	mux.HandleFunc("/api/scoreboard/swap", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, "POST")
+			return
+		}
+		updateScoreboard(w, state, func(sb *Scoreboard) error {
+			sb.SwapPlayers()
+			return nil
+		})
+	})
+
+	mux.HandleFunc("/api/scoreboard/undo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, "POST")
+			return
+		}
+		respondUndo(w, state.Undo)
+	})
+
+	mux.HandleFunc("/api/scoreboard/redo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, "POST")
+			return
+		}
+		respondUndo(w, state.Redo)
+	})
+
+	mux.HandleFunc("/api/players", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, "GET")
+			return
+		}
+		writeJSON(w, http.StatusOK, catalog.FindPlayers(r.URL.Query().Get("q")))
+	})
+
+	mux.HandleFunc("/api/characters", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, "GET")
+			return
+		}
+		writeJSON(w, http.StatusOK, catalog.Characters())
+	})
+
+	mux.HandleFunc("/api/stages", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, "GET")
+			return
+		}
+		writeJSON(w, http.StatusOK, catalog.Stages())
+	})
+
+	mux.HandleFunc("/api/history/sets", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, "GET")
+			return
+		}
+		day := time.Now()
+		if d := r.URL.Query().Get("date"); d != "" {
+			var err error
+			day, err = time.ParseInLocation(DayFormat, d, time.Local)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid date: %q", d))
+				return
+			}
+		}
+		sets, err := state.History().Sets(day)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		switch format := r.URL.Query().Get("format"); format {
+		case "", "json":
+			writeJSON(w, http.StatusOK, sets)
+		case "csv":
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Cache-Control", "no-cache")
+			WriteSetsCSV(w, sets)
+		default:
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid format: %q", format))
+		}
+	})
+
+	return protectAPI(mux, token)
+}
+
+// protectAPI rejects requests from other web pages, which browsers would
+// otherwise happily send on behalf of any site the operator has open, and
+// requests without token, if there is one. It goes in the Authorization
+// header as "Bearer token", or in the query string as ?token= for clients
+// that can't set headers.
+func protectAPI(h http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if crossOrigin(r) {
+			writeError(w, http.StatusForbidden, errors.New("cross-origin request"))
+			return
+		}
+		if token != "" {
+			given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if given == "" {
+				given = r.URL.Query().Get("token")
+			}
+			if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("invalid token"))
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// crossOrigin tells whether r was sent by a browser from another site.
+// Scripts and Stream Deck buttons don't send Origin at all.
+func crossOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site == "cross-site" {
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err != nil || u.Host != r.Host
+}
+
+func updateScoreboard(w http.ResponseWriter, state *State, fn func(*Scoreboard) error) {
+	scoreboard, err := state.Update(fn)
+	if errors.Is(err, ErrNotSaved) {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, scoreboard)
+}
+
+// respondUndo runs State.Undo or Redo. Having nothing left to undo is a
+// conflict with the current state rather than a bad request.
+func respondUndo(w http.ResponseWriter, fn func() (Scoreboard, error)) {
+	scoreboard, err := fn()
+	if errors.Is(err, ErrNotSaved) {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, scoreboard)
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	raw, err := readJSON(r)
+	if err != nil {
+		return err
+	}
+	return decodeRawJSON(raw, v)
+}
+
+// readJSON reads a JSON value from r's body, rejecting null, which would
+// otherwise silently decode to nothing, e.g. wipe the whole scoreboard on PUT.
+func readJSON(r *http.Request) (json.RawMessage, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid json body: %w", err)
+	}
+	if bytes.Equal(raw, []byte("null")) {
+		return nil, errors.New("invalid json body: null")
+	}
+	return raw, nil
+}
+
+func decodeRawJSON(raw json.RawMessage, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid json body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
@@ -0,0 +1,99 @@
+// Package bracket describes a tournament bracket of any size, independent of
+// where it came from. It's what gets written to bracket.json for the overlay.
+package bracket
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type Type string
+
+const (
+	SingleElimination Type = "single_elimination"
+	DoubleElimination Type = "double_elimination"
+	RoundRobin        Type = "round_robin"
+)
+
+type Side string
+
+const (
+	Winners    Side = "winners"
+	Losers     Side = "losers"
+	GrandFinal Side = "grandfinal"
+)
+
+type State string
+
+const (
+	Pending    State = "pending"
+	InProgress State = "inprogress"
+	Completed  State = "completed"
+)
+
+type Bracket struct {
+	Name string `json:"name"`
+	Type Type   `json:"type"`
+	Sets []Set  `json:"sets"`
+}
+
+type Set struct {
+	ID string `json:"id"`
+	// Short human-readable identifier e.g. "A", "B", ...
+	Identifier string `json:"identifier"`
+	// Winners rounds count up from 1, losers rounds count down from -1.
+	// Grand final (and its reset) are the last winners rounds.
+	Round     int    `json:"round"`
+	Side      Side   `json:"side"`
+	RoundText string `json:"roundtext"`
+	State     State  `json:"state"`
+	// True for the second grand final set,
+	// only played if the losers side finalist wins the first one.
+	Reset bool `json:"reset"`
+	// True for the set to play next, if the bracket's source knows it.
+	Next  bool    `json:"next"`
+	Slots [2]Slot `json:"slots"`
+}
+
+type Placement string
+
+const (
+	Winner Placement = "winner"
+	Loser  Placement = "loser"
+)
+
+type Slot struct {
+	// Empty if not yet known.
+	EntrantID string `json:"entrantid"`
+	Entrant   string `json:"entrant"`
+	// Nil if not reported yet. Negative means disqualified.
+	Score  *int `json:"score"`
+	Winner bool `json:"winner"`
+	// Which set's winner or loser goes into this slot.
+	// Empty for first round slots, which are filled by seeding.
+	PrereqSetID     string    `json:"prereqsetid"`
+	PrereqPlacement Placement `json:"prereqplacement"`
+}
+
+// Winner returns the index of the slot that won this set, or -1.
+func (s *Set) Winner() int {
+	for i, slot := range s.Slots {
+		if slot.Winner {
+			return i
+		}
+	}
+	return -1
+}
+
+func Write(filepath string, b Bracket) error {
+	blob, err := json.MarshalIndent(b, "", "    ")
+	if err != nil {
+		return fmt.Errorf("write bracket: %w", err)
+	}
+	err = os.WriteFile(filepath, blob, 0644)
+	if err != nil {
+		return fmt.Errorf("write bracket: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"sync"
+
+	"go.imnhan.com/gorts/players"
+)
+
+// Catalog holds the known players, characters and stages that control
+// surfaces offer as suggestions. Players can be replaced at runtime (e.g.
+// after a start.gg import) while the HTTP API is reading them, hence the
+// lock.
+type Catalog struct {
+	mu         sync.RWMutex
+	players    []players.Player
+	characters []string
+	stages     []string
+}
+
+// LoadCatalog returns whatever it could load, along with an error for each
+// file that it couldn't.
+func LoadCatalog() (*Catalog, error) {
+	ps, playersErr := players.FromFile(PlayersFile)
+	characters, charactersErr := FromCSVFile(CharactersFile)
+	stages, stagesErr := FromCSVFile(StagesFile)
+	catalog := &Catalog{
+		players:    ps,
+		characters: characters,
+		stages:     stages,
+	}
+	return catalog, errors.Join(playersErr, charactersErr, stagesErr)
+}
+
+func (c *Catalog) SetPlayers(ps []players.Player) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.players = ps
+}
+
+func (c *Catalog) NumPlayers() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.players)
+}
+
+// FindPlayers returns all players whose names match query,
+// or all players if query is empty.
+func (c *Catalog) FindPlayers(query string) []players.Player {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make([]players.Player, 0)
+	for _, p := range c.players {
+		if query == "" || p.MatchesName(query) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// SearchPlayers is like FindPlayers but only returns names.
+func (c *Catalog) SearchPlayers(query string) []string {
+	names := make([]string, 0)
+	for _, p := range c.FindPlayers(query) {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// FindPlayer returns the player with exactly this name, if any.
+func (c *Catalog) FindPlayer(name string) (players.Player, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, p := range c.players {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return players.Player{}, false
+}
+
+// Characters and stages are never modified after loading,
+// so it's safe to hand out the slices directly.
+
+func (c *Catalog) Characters() []string {
+	return c.characters
+}
+
+func (c *Catalog) Stages() []string {
+	return c.stages
+}
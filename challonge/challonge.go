@@ -0,0 +1,282 @@
+// Package challonge imports players, matches and brackets from Challonge
+// and reports results back, using its v1 REST API.
+// See https://api.challonge.com/v1
+package challonge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultURL = "https://api.challonge.com/v1"
+
+const DefaultTimeout = 30 * time.Second
+
+type Client struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+
+	// APIKey may be changed by SetAPIKey while requests are in flight.
+	mu sync.Mutex
+}
+
+func NewClient(apiKey string) *Client {
+	return &Client{
+		BaseURL:    DefaultURL,
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+// SetAPIKey changes the key that requests are sent with from now on. Unlike
+// setting APIKey directly, it's safe to call while requests are in flight.
+func (c *Client) SetAPIKey(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.APIKey = key
+}
+
+func (c *Client) apiKey() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.APIKey
+}
+
+// APIError is a non-200 response from Challonge, which usually comes with
+// a list of human-readable errors.
+type APIError struct {
+	StatusCode int
+	Messages   []string
+}
+
+func (e *APIError) Error() string {
+	if len(e.Messages) == 0 {
+		return fmt.Sprintf("Challonge responded with status %d", e.StatusCode)
+	}
+	return strings.Join(e.Messages, "; ")
+}
+
+// TournamentId extracts the tournament's API identifier from whatever the
+// user pasted: its URL, e.g. https://challonge.com/my_weekly_42 or
+// https://myorg.challonge.com/my_weekly_42, or the identifier itself.
+// Tournaments hosted under an organization's subdomain are identified as
+// "subdomain-url", e.g. "myorg-my_weekly_42".
+func TournamentId(input string) string {
+	id := strings.TrimSpace(input)
+	if i := strings.Index(id, "://"); i != -1 {
+		id = id[i+3:]
+	}
+	parts := strings.Split(strings.Trim(id, "/"), "/")
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	host, path := parts[0], parts[len(parts)-1]
+	subdomain := strings.TrimSuffix(host, "challonge.com")
+	subdomain = strings.TrimSuffix(subdomain, ".")
+	if subdomain == "" || subdomain == "www" || subdomain == host {
+		return path
+	}
+	return subdomain + "-" + path
+}
+
+// request sends form (if any) to path, which is relative to BaseURL, and
+// decodes the JSON response into result.
+func (c *Client) request(
+	ctx context.Context, method, path string, form url.Values, result any,
+) error {
+	query := url.Values{"api_key": {c.apiKey()}}
+	if method == http.MethodGet {
+		for k, v := range form {
+			query[k] = v
+		}
+		form = nil
+	}
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(
+		ctx, method, c.BaseURL+path+"?"+query.Encode(), body,
+	)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Add("User-Agent", "GORTS/0.5")
+	if form != nil {
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		// Its URL has the API key in it, and the error ends up on screen.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("Error making API request to %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	respdata, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Error reading API response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		respJson := struct {
+			Errors []string `json:"errors"`
+		}{}
+		if json.Unmarshal(respdata, &respJson) == nil {
+			apiErr.Messages = respJson.Errors
+		}
+		return apiErr
+	}
+
+	err = json.Unmarshal(respdata, result)
+	if err != nil {
+		return fmt.Errorf("unexpected response: %s", respdata)
+	}
+	return nil
+}
+
+type tournamentJson struct {
+	Id             int    `json:"id"`
+	Name           string `json:"name"`
+	TournamentType string `json:"tournament_type"`
+	Participants   []struct {
+		Participant participantJson `json:"participant"`
+	} `json:"participants"`
+	Matches []struct {
+		Match matchJson `json:"match"`
+	} `json:"matches"`
+}
+
+type participantJson struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	// Matches of a group stage refer to participants by these instead.
+	GroupPlayerIds []int `json:"group_player_ids"`
+}
+
+func (p participantJson) name() string {
+	if p.DisplayName != "" {
+		return p.DisplayName
+	}
+	return p.Name
+}
+
+type matchJson struct {
+	Id         int    `json:"id"`
+	Identifier string `json:"identifier"`
+	// Winners rounds count up from 1, losers rounds count down from -1.
+	Round int `json:"round"`
+	// "pending" until both players are known, then "open", then "complete".
+	State      string  `json:"state"`
+	UnderwayAt *string `json:"underway_at"`
+	Player1Id  *int    `json:"player1_id"`
+	Player2Id  *int    `json:"player2_id"`
+	WinnerId   *int    `json:"winner_id"`
+	// e.g. "3-1", or one score per game: "1-0,0-1,1-0"
+	ScoresCsv                 string `json:"scores_csv"`
+	Player1PrereqMatchId      *int   `json:"player1_prereq_match_id"`
+	Player2PrereqMatchId      *int   `json:"player2_prereq_match_id"`
+	Player1IsPrereqMatchLoser bool   `json:"player1_is_prereq_match_loser"`
+	Player2IsPrereqMatchLoser bool   `json:"player2_is_prereq_match_loser"`
+	SuggestedPlayOrder        *int   `json:"suggested_play_order"`
+}
+
+func (m matchJson) playerIds() [2]*int {
+	return [2]*int{m.Player1Id, m.Player2Id}
+}
+
+// scores returns each player's score, or false if there is none yet.
+func (m matchJson) scores() ([2]int, bool) {
+	var result [2]int
+	games := strings.Split(m.ScoresCsv, ",")
+	if m.ScoresCsv == "" {
+		return result, false
+	}
+	for _, game := range games {
+		if game == "" {
+			return result, false
+		}
+		// Scores may be negative, e.g. "-1-3".
+		i := strings.Index(game[1:], "-") + 1
+		if i == 0 {
+			return result, false
+		}
+		a, errA := strconv.Atoi(game[:i])
+		b, errB := strconv.Atoi(game[i+1:])
+		if errA != nil || errB != nil {
+			return result, false
+		}
+		if len(games) == 1 {
+			return [2]int{a, b}, true
+		}
+		switch {
+		case a > b:
+			result[0]++
+		case b > a:
+			result[1]++
+		}
+	}
+	return result, true
+}
+
+func (c *Client) fetchTournament(ctx context.Context, tournament string) (tournamentJson, error) {
+	var respJson struct {
+		Tournament tournamentJson `json:"tournament"`
+	}
+	err := c.request(
+		ctx, http.MethodGet,
+		"/tournaments/"+url.PathEscape(TournamentId(tournament))+".json",
+		url.Values{
+			"include_participants": {"1"},
+			"include_matches":      {"1"},
+		},
+		&respJson,
+	)
+	if err != nil {
+		return tournamentJson{}, fmt.Errorf("fetch tournament: %w", err)
+	}
+	return respJson.Tournament, nil
+}
+
+// ReportMatch sets a match's final score, in player 1 - player 2 order, and
+// its winner.
+func (c *Client) ReportMatch(
+	ctx context.Context, tournament, matchId string, scores [2]int, winnerId string,
+) error {
+	var respJson struct {
+		Match matchJson `json:"match"`
+	}
+	err := c.request(
+		ctx, http.MethodPut,
+		fmt.Sprintf(
+			"/tournaments/%s/matches/%s.json",
+			url.PathEscape(TournamentId(tournament)), url.PathEscape(matchId),
+		),
+		url.Values{
+			"match[scores_csv]": {fmt.Sprintf("%d-%d", scores[0], scores[1])},
+			"match[winner_id]":  {winnerId},
+		},
+		&respJson,
+	)
+	if err != nil {
+		return fmt.Errorf("report match: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package challonge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestScores(t *testing.T) {
+	for _, tc := range []struct {
+		csv  string
+		want [2]int
+		ok   bool
+	}{
+		{"", [2]int{}, false},
+		{"3-1", [2]int{3, 1}, true},
+		{"0-0", [2]int{0, 0}, true},
+		// Negative means disqualified.
+		{"-1-2", [2]int{-1, 2}, true},
+		{"2--1", [2]int{2, -1}, true},
+		// One score per game, counted as games won.
+		{"1-0,0-1,1-0", [2]int{2, 1}, true},
+		{"3-5,5-3,2-5", [2]int{1, 2}, true},
+		// Tied games don't count for anyone.
+		{"1-1,2-0", [2]int{1, 0}, true},
+		{"3-", [2]int{}, false},
+		{"-3", [2]int{}, false},
+		{"-", [2]int{}, false},
+		{"3", [2]int{}, false},
+		{"a-b", [2]int{}, false},
+		{"1-0,", [2]int{}, false},
+		{"1-0,,0-1", [2]int{}, false},
+	} {
+		got, ok := matchJson{ScoresCsv: tc.csv}.scores()
+		if ok != tc.ok || (ok && got != tc.want) {
+			t.Errorf("scores(%q) = %v, %t, want %v, %t", tc.csv, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestTournamentId(t *testing.T) {
+	for input, want := range map[string]string{
+		"my_weekly_42":                           "my_weekly_42",
+		" my_weekly_42 ":                         "my_weekly_42",
+		"https://challonge.com/my_weekly_42":     "my_weekly_42",
+		"https://challonge.com/my_weekly_42/":    "my_weekly_42",
+		"challonge.com/my_weekly_42":             "my_weekly_42",
+		"https://www.challonge.com/my_weekly_42": "my_weekly_42",
+		"https://myorg.challonge.com/weekly":     "myorg-weekly",
+		"https://challonge.com/fr/weekly":        "weekly",
+	} {
+		if got := TournamentId(input); got != want {
+			t.Errorf("TournamentId(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestRequestErrorHidesKey(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	c := NewClient("s3cret")
+	c.BaseURL = server.URL
+	var result any
+	err := c.request(context.Background(), http.MethodGet, "/tournaments.json", nil, &result)
+	if err == nil {
+		t.Fatal("expected an error from a closed server")
+	}
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("API key in error: %s", err)
+	}
+}
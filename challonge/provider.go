@@ -0,0 +1,303 @@
+package challonge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.imnhan.com/gorts/bracket"
+	"go.imnhan.com/gorts/players"
+	"go.imnhan.com/gorts/tournament"
+)
+
+// Provider makes Challonge a tournament.Provider.
+type Provider struct {
+	Client     *Client
+	Tournament string
+}
+
+func (p *Provider) FetchPlayers(
+	ctx context.Context, progress func(fetched, total int),
+) ([]players.Player, error) {
+	t, err := p.Client.fetchTournament(ctx, p.Tournament)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]players.Player, 0, len(t.Participants))
+	for _, part := range t.Participants {
+		results = append(results, players.Player{Name: part.Participant.name()})
+	}
+	if progress != nil {
+		progress(len(results), len(results))
+	}
+	return results, nil
+}
+
+// FetchMatches returns every match that hasn't been completed yet: those
+// underway first, then in Challonge's suggested play order.
+func (p *Provider) FetchMatches(ctx context.Context) ([]tournament.Match, error) {
+	t, err := p.Client.fetchTournament(ctx, p.Tournament)
+	if err != nil {
+		return nil, err
+	}
+	b := newBracketInfo(t)
+
+	pending := make([]matchJson, 0)
+	for _, m := range t.Matches {
+		if m.Match.State != "complete" {
+			pending = append(pending, m.Match)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		a, b := pending[i], pending[j]
+		if (a.UnderwayAt != nil) != (b.UnderwayAt != nil) {
+			return a.UnderwayAt != nil
+		}
+		return playOrder(a) < playOrder(b)
+	})
+
+	matches := make([]tournament.Match, 0, len(pending))
+	for _, m := range pending {
+		matches = append(matches, b.toMatch(m))
+	}
+	return matches, nil
+}
+
+func playOrder(m matchJson) int {
+	if m.SuggestedPlayOrder == nil {
+		return m.Id
+	}
+	return *m.SuggestedPlayOrder
+}
+
+func (p *Provider) FetchMatch(ctx context.Context, id string) (tournament.Match, error) {
+	t, err := p.Client.fetchTournament(ctx, p.Tournament)
+	if err != nil {
+		return tournament.Match{}, err
+	}
+	b := newBracketInfo(t)
+	for _, m := range t.Matches {
+		if strconv.Itoa(m.Match.Id) == id {
+			return b.toMatch(m.Match), nil
+		}
+	}
+	return tournament.Match{}, fmt.Errorf("Match %s not found on Challonge", id)
+}
+
+func (p *Provider) FetchBracket(ctx context.Context) (bracket.Bracket, error) {
+	t, err := p.Client.fetchTournament(ctx, p.Tournament)
+	if err != nil {
+		return bracket.Bracket{}, err
+	}
+	if len(t.Matches) == 0 {
+		return bracket.Bracket{}, fmt.Errorf(
+			"Tournament %s has no matches. Has it been started?", t.Name,
+		)
+	}
+	b := newBracketInfo(t)
+	result := bracket.Bracket{Name: t.Name, Type: bracketType(t.TournamentType)}
+	for _, m := range t.Matches {
+		result.Sets = append(result.Sets, b.toSet(m.Match))
+	}
+	return result, nil
+}
+
+func (p *Provider) Report(ctx context.Context, result tournament.Result) error {
+	winner := result.Winner()
+	if winner == -1 {
+		return fmt.Errorf("Scores are tied, there's no winner to report.")
+	}
+	match := result.Match
+	return p.Client.ReportMatch(
+		ctx, p.Tournament, match.Id, result.Scores, match.EntrantIds[winner],
+	)
+}
+
+func bracketType(raw string) bracket.Type {
+	return bracket.Type(strings.ReplaceAll(raw, " ", "_"))
+}
+
+// bracketInfo is what we need to know about the whole tournament to describe
+// any of its matches: who's who, and how many rounds there are.
+type bracketInfo struct {
+	doubleElimination bool
+	// Keyed by both participant ids and group player ids.
+	participants map[int]participantJson
+	matches      map[int]matchJson
+	// Last winners round before grand final, first (lowest) losers round.
+	maxRound, minRound int
+}
+
+func newBracketInfo(t tournamentJson) bracketInfo {
+	b := bracketInfo{
+		doubleElimination: bracketType(t.TournamentType) == bracket.DoubleElimination,
+		participants:      make(map[int]participantJson),
+		matches:           make(map[int]matchJson),
+	}
+	for _, p := range t.Participants {
+		b.participants[p.Participant.Id] = p.Participant
+		for _, id := range p.Participant.GroupPlayerIds {
+			b.participants[id] = p.Participant
+		}
+	}
+	for _, m := range t.Matches {
+		b.matches[m.Match.Id] = m.Match
+	}
+	for _, m := range t.Matches {
+		if b.isGrandFinal(m.Match) || b.isReset(m.Match) {
+			continue
+		}
+		if m.Match.Round > b.maxRound {
+			b.maxRound = m.Match.Round
+		}
+		if m.Match.Round < b.minRound {
+			b.minRound = m.Match.Round
+		}
+	}
+	return b
+}
+
+// isGrandFinal reports whether m is where the winners side meets the losers
+// side.
+func (b bracketInfo) isGrandFinal(m matchJson) bool {
+	if !b.doubleElimination || m.Round <= 0 {
+		return false
+	}
+	for _, prereq := range []*int{m.Player1PrereqMatchId, m.Player2PrereqMatchId} {
+		if prereq != nil && b.matches[*prereq].Round < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// isReset reports whether m is the second grand final, which both players
+// come from the first one.
+func (b bracketInfo) isReset(m matchJson) bool {
+	p1, p2 := m.Player1PrereqMatchId, m.Player2PrereqMatchId
+	return b.doubleElimination && p1 != nil && p2 != nil && *p1 == *p2 &&
+		b.isGrandFinal(b.matches[*p1])
+}
+
+// roundText names rounds the way start.gg does, so that the same round name
+// mappings work for both.
+func (b bracketInfo) roundText(m matchJson) string {
+	switch {
+	case b.isReset(m):
+		return "Grand Final Reset"
+	case b.isGrandFinal(m):
+		return "Grand Final"
+	}
+
+	side := ""
+	if b.doubleElimination {
+		side = "Winners "
+	}
+	fromLast := b.maxRound - m.Round
+	if m.Round < 0 {
+		side = "Losers "
+		fromLast = m.Round - b.minRound
+	}
+	if !b.doubleElimination && b.minRound == 0 && m.Round > 0 {
+		switch fromLast {
+		case 0:
+			return "Final"
+		case 1:
+			return "Semi-Final"
+		case 2:
+			return "Quarter-Final"
+		}
+	} else {
+		switch fromLast {
+		case 0:
+			return side + "Final"
+		case 1:
+			return side + "Semi-Final"
+		}
+	}
+	if m.Round < 0 {
+		return fmt.Sprintf("%sRound %d", side, -m.Round)
+	}
+	return fmt.Sprintf("%sRound %d", side, m.Round)
+}
+
+func (b bracketInfo) toMatch(m matchJson) tournament.Match {
+	result := tournament.Match{
+		Id:        strconv.Itoa(m.Id),
+		RoundText: b.roundText(m),
+		State:     matchState(m),
+	}
+	for i, id := range m.playerIds() {
+		if id == nil {
+			continue
+		}
+		part, ok := b.participants[*id]
+		if !ok {
+			continue
+		}
+		result.EntrantIds[i] = strconv.Itoa(*id)
+		result.Entrants[i] = players.Single(players.Player{Name: part.name()})
+	}
+	return result
+}
+
+func (b bracketInfo) toSet(m matchJson) bracket.Set {
+	result := bracket.Set{
+		ID:         strconv.Itoa(m.Id),
+		Identifier: m.Identifier,
+		Round:      m.Round,
+		Side:       bracket.Winners,
+		RoundText:  b.roundText(m),
+		State:      matchState(m),
+	}
+	switch {
+	case b.isReset(m):
+		result.Side = bracket.GrandFinal
+		result.Round = b.maxRound + 2
+		result.Reset = true
+	case b.isGrandFinal(m):
+		result.Side = bracket.GrandFinal
+		result.Round = b.maxRound + 1
+	case m.Round < 0:
+		result.Side = bracket.Losers
+	}
+
+	scores, hasScores := m.scores()
+	prereqs := [2]*int{m.Player1PrereqMatchId, m.Player2PrereqMatchId}
+	isLoser := [2]bool{m.Player1IsPrereqMatchLoser, m.Player2IsPrereqMatchLoser}
+	for i, id := range m.playerIds() {
+		slot := &result.Slots[i]
+		if id != nil {
+			if part, ok := b.participants[*id]; ok {
+				slot.EntrantID = strconv.Itoa(*id)
+				slot.Entrant = part.name()
+			}
+			slot.Winner = m.WinnerId != nil && *m.WinnerId == *id
+		}
+		if hasScores {
+			score := scores[i]
+			slot.Score = &score
+		}
+		if prereqs[i] != nil {
+			slot.PrereqSetID = strconv.Itoa(*prereqs[i])
+			slot.PrereqPlacement = bracket.Winner
+			if isLoser[i] {
+				slot.PrereqPlacement = bracket.Loser
+			}
+		}
+	}
+	return result
+}
+
+func matchState(m matchJson) bracket.State {
+	switch {
+	case m.State == "complete":
+		return bracket.Completed
+	case m.UnderwayAt != nil:
+		return bracket.InProgress
+	default:
+		return bracket.Pending
+	}
+}
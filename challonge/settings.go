@@ -0,0 +1,86 @@
+package challonge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Inputs are everything the user entered on the Challonge tab. Like
+// start.gg's token, the API key is kept in its own private file.
+type Inputs struct {
+	APIKey string `json:"-"`
+	// Tournament URL or API identifier, see TournamentId.
+	Tournament string `json:"tournament"`
+}
+
+func DefaultKeyPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "challonge-key"
+	}
+	return filepath.Join(dir, "gorts", "challonge-key")
+}
+
+// LoadInputs reads settings and API key from their own files.
+// Missing files are not an error.
+func LoadInputs(settingsPath, keyPath string) (Inputs, error) {
+	var result Inputs
+
+	blob, err := os.ReadFile(settingsPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return result, fmt.Errorf("load challonge settings: %w", err)
+	}
+	if err == nil {
+		err = json.Unmarshal(blob, &result)
+		if err != nil {
+			return result, fmt.Errorf("load challonge settings from %s: %w", settingsPath, err)
+		}
+	}
+
+	key, err := os.ReadFile(keyPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return result, fmt.Errorf("load challonge api key: %w", err)
+	}
+	result.APIKey = strings.TrimSpace(string(key))
+	return result, nil
+}
+
+// Write saves settings, and the API key if there is one. An empty key
+// deletes the key file.
+func (i *Inputs) Write(settingsPath, keyPath string) error {
+	blob, err := json.MarshalIndent(i, "", "    ")
+	if err != nil {
+		return fmt.Errorf("write challonge settings: %w", err)
+	}
+	err = os.WriteFile(settingsPath, blob, 0644)
+	if err != nil {
+		return fmt.Errorf("write challonge settings: %w", err)
+	}
+
+	if i.APIKey == "" {
+		err = os.Remove(keyPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("delete challonge api key: %w", err)
+		}
+		return nil
+	}
+	err = os.MkdirAll(filepath.Dir(keyPath), 0700)
+	if err != nil {
+		return fmt.Errorf("write challonge api key: %w", err)
+	}
+	err = os.WriteFile(keyPath, []byte(i.APIKey), 0600)
+	if err != nil {
+		return fmt.Errorf("write challonge api key: %w", err)
+	}
+	// WriteFile only sets permissions on new files.
+	err = os.Chmod(keyPath, 0600)
+	if err != nil {
+		return fmt.Errorf("write challonge api key: %w", err)
+	}
+	return nil
+}
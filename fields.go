@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FieldType tells the GUI what widget to show for a custom field, and how
+// its value is checked before being applied.
+type FieldType string
+
+const (
+	TextField FieldType = "text"
+	// Whole number, 0 or more.
+	ScoreField FieldType = "score"
+	// 2-letter code, e.g. "jp".
+	CountryField   FieldType = "country"
+	CharacterField FieldType = "character"
+	PlayerField    FieldType = "player"
+)
+
+var FieldTypes = []FieldType{
+	TextField, ScoreField, CountryField, CharacterField, PlayerField,
+}
+
+// Field is a custom scoreboard field, on top of the built-in ones.
+type Field struct {
+	// Key in state.json, and id of the overlay element it's drawn into.
+	Name    string    `json:"name"`
+	Type    FieldType `json:"type"`
+	Label   string    `json:"label"`
+	Default string    `json:"default"`
+}
+
+// Schema lists custom fields in the order the GUI shows them.
+type Schema []Field
+
+var fieldNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// builtinFields are the JSON keys of Scoreboard's own fields, which custom
+// fields must not clash with.
+var builtinFields = func() map[string]bool {
+	result := make(map[string]bool)
+	t := reflect.TypeOf(Scoreboard{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			result[name] = true
+		}
+	}
+	return result
+}()
+
+// LoadSchema reads a csv file of name,type,label,default rows. Label
+// defaults to name, and default to the type's zero value. It returns an
+// empty schema if the file does not exist.
+func LoadSchema(filepath string) (Schema, error) {
+	result := make(Schema, 0)
+	f, err := os.Open(filepath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("load fields: %w", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return result, fmt.Errorf("load fields from %s: %w", filepath, err)
+	}
+
+	for i, record := range records {
+		if len(record) < 2 || len(record) > 4 {
+			return make(Schema, 0), fmt.Errorf(
+				"load fields from %s: line %d: want 2 to 4 columns, got %d",
+				filepath, i+1, len(record),
+			)
+		}
+		field := Field{
+			Name: strings.TrimSpace(record[0]),
+			Type: FieldType(strings.TrimSpace(record[1])),
+		}
+		if len(record) > 2 {
+			field.Label = strings.TrimSpace(record[2])
+		}
+		if field.Label == "" {
+			field.Label = field.Name
+		}
+		if len(record) > 3 {
+			field.Default = record[3]
+		}
+		if err := result.check(field); err != nil {
+			return make(Schema, 0), fmt.Errorf(
+				"load fields from %s: line %d: %w", filepath, i+1, err,
+			)
+		}
+		field.Default, _ = field.Validate(field.Default)
+		result = append(result, field)
+	}
+	return result, nil
+}
+
+// check makes sure field can be added to s.
+func (s Schema) check(field Field) error {
+	if !fieldNameRegex.MatchString(field.Name) {
+		return fmt.Errorf(
+			"invalid field name %q: use lowercase letters, digits and _",
+			field.Name,
+		)
+	}
+	if builtinFields[field.Name] {
+		return fmt.Errorf("field %s is built-in", field.Name)
+	}
+	if _, ok := s.Field(field.Name); ok {
+		return fmt.Errorf("field %s is defined twice", field.Name)
+	}
+	validType := false
+	for _, t := range FieldTypes {
+		validType = validType || field.Type == t
+	}
+	if !validType {
+		return fmt.Errorf("field %s has invalid type %q", field.Name, field.Type)
+	}
+	if _, err := field.Validate(field.Default); err != nil {
+		return fmt.Errorf("default of %w", err)
+	}
+	return nil
+}
+
+func (s Schema) Field(name string) (Field, bool) {
+	for _, f := range s {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+func (s Schema) Defaults() map[string]string {
+	result := make(map[string]string, len(s))
+	for _, f := range s {
+		result[f.Name] = f.Default
+	}
+	return result
+}
+
+// Normalize returns a copy of values with exactly the schema's fields:
+// missing ones get their default, and the others are checked against their
+// type. Unknown fields are an error, since they're most likely typos.
+func (s Schema) Normalize(values map[string]string) (map[string]string, error) {
+	for name := range values {
+		if _, ok := s.Field(name); !ok {
+			return nil, fmt.Errorf("unknown field: %q", name)
+		}
+	}
+	result := make(map[string]string, len(s))
+	for _, f := range s {
+		value, ok := values[f.Name]
+		if !ok {
+			result[f.Name] = f.Default
+			continue
+		}
+		value, err := f.Validate(value)
+		if err != nil {
+			return nil, err
+		}
+		result[f.Name] = value
+	}
+	return result, nil
+}
+
+// Validate returns value cleaned up for the field's type, e.g. " 02" for a
+// score becomes "2".
+func (f Field) Validate(value string) (string, error) {
+	switch f.Type {
+	case ScoreField:
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return "0", nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf(
+				"field %s must be a whole number of 0 or more, got %q",
+				f.Name, value,
+			)
+		}
+		return strconv.Itoa(n), nil
+	case CountryField:
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			return "", nil
+		}
+		if len(value) != 2 || value[0] < 'a' || value[0] > 'z' ||
+			value[1] < 'a' || value[1] > 'z' {
+			return "", fmt.Errorf(
+				"field %s must be a 2-letter country code, got %q",
+				f.Name, value,
+			)
+		}
+		return value, nil
+	}
+	return value, nil
+}
+
+// scoreboardJSON is Scoreboard without its custom JSON methods, so they can
+// do the built-in fields the default way.
+type scoreboardJSON Scoreboard
+
+// MarshalJSON puts custom fields next to built-in ones, so overlays can use
+// both the same way.
+func (s Scoreboard) MarshalJSON() ([]byte, error) {
+	blob, err := json.Marshal(scoreboardJSON(s))
+	if err != nil || len(s.Custom) == 0 {
+		return blob, err
+	}
+	custom, err := json.Marshal(s.Custom)
+	if err != nil {
+		return nil, err
+	}
+	// Both are objects: replace the closing brace of one with the other's
+	// fields.
+	blob = append(blob[:len(blob)-1], ',')
+	return append(blob, custom[1:]...), nil
+}
+
+// UnmarshalJSON decodes on top of what's already in s, like the default
+// does, so that only the given custom fields are changed. Anything that
+// isn't a built-in field is taken as a custom one, for the schema to check.
+func (s *Scoreboard) UnmarshalJSON(data []byte) error {
+	err := json.Unmarshal(data, (*scoreboardJSON)(s))
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	// Scoreboards are copied around by value (e.g. for undo), so the map
+	// they share must never be modified in place.
+	custom := make(map[string]string, len(s.Custom))
+	for name, value := range s.Custom {
+		custom[name] = value
+	}
+	for name, raw := range fields {
+		if builtinFields[name] {
+			continue
+		}
+		var value string
+		if json.Unmarshal(raw, &value) != nil {
+			// Also take numbers, for score fields.
+			var number json.Number
+			if json.Unmarshal(raw, &number) != nil {
+				return fmt.Errorf("field %s must be a string or number", name)
+			}
+			value = number.String()
+		}
+		custom[name] = value
+	}
+	s.Custom = custom
+	return nil
+}
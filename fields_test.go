@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testSchema = Schema{
+	{Name: "pool", Type: TextField, Label: "Pool", Default: "A"},
+	{Name: "games", Type: ScoreField, Label: "Games", Default: "0"},
+	{Name: "c1flag", Type: CountryField, Label: "Caster 1 country"},
+}
+
+func TestNormalize(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		values map[string]string
+		want   map[string]string
+		// Substring of the error, if any.
+		err string
+	}{
+		{
+			name:   "missing fields get defaults",
+			values: map[string]string{},
+			want:   map[string]string{"pool": "A", "games": "0", "c1flag": ""},
+		},
+		{
+			name:   "nil is like empty",
+			values: nil,
+			want:   map[string]string{"pool": "A", "games": "0", "c1flag": ""},
+		},
+		{
+			name:   "values are cleaned up",
+			values: map[string]string{"pool": " B ", "games": " 02", "c1flag": " JP "},
+			want:   map[string]string{"pool": " B ", "games": "2", "c1flag": "jp"},
+		},
+		{
+			name:   "empty score is 0",
+			values: map[string]string{"games": " "},
+			want:   map[string]string{"pool": "A", "games": "0", "c1flag": ""},
+		},
+		{
+			name:   "unknown field",
+			values: map[string]string{"pol": "B"},
+			err:    `unknown field: "pol"`,
+		},
+		{
+			name:   "negative score",
+			values: map[string]string{"games": "-1"},
+			err:    "field games must be a whole number",
+		},
+		{
+			name:   "score that isn't a number",
+			values: map[string]string{"games": "two"},
+			err:    "field games must be a whole number",
+		},
+		{
+			name:   "3-letter country",
+			values: map[string]string{"c1flag": "jpn"},
+			err:    "field c1flag must be a 2-letter country code",
+		},
+		{
+			name:   "country that isn't letters",
+			values: map[string]string{"c1flag": "j1"},
+			err:    "field c1flag must be a 2-letter country code",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := testSchema.Normalize(tc.values)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got error %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeDoesNotModifyValues(t *testing.T) {
+	values := map[string]string{"games": "02"}
+	testSchema.Normalize(values)
+	if values["games"] != "02" || len(values) != 1 {
+		t.Errorf("values modified: %v", values)
+	}
+}
+
+func TestLoadSchema(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		csv  string
+		want Schema
+		err  string
+	}{
+		{
+			name: "label and default are optional",
+			csv:  "pool,text\ngames,score,Games,3\nc1flag,country,,JP\n",
+			want: Schema{
+				{Name: "pool", Type: TextField, Label: "pool"},
+				{Name: "games", Type: ScoreField, Label: "Games", Default: "3"},
+				{Name: "c1flag", Type: CountryField, Label: "c1flag", Default: "jp"},
+			},
+		},
+		{name: "invalid name", csv: "Pool,text\n", err: "invalid field name"},
+		{name: "built-in name", csv: "p1name,text\n", err: "field p1name is built-in"},
+		{name: "defined twice", csv: "pool,text\npool,text\n", err: "defined twice"},
+		{name: "invalid type", csv: "pool,txt\n", err: `invalid type "txt"`},
+		{name: "invalid default", csv: "games,score,Games,x\n", err: "default of field games"},
+		{name: "too few columns", csv: "pool\n", err: "line 1: want 2 to 4 columns"},
+		{name: "too many columns", csv: "pool,text,Pool,A,B\n", err: "want 2 to 4 columns"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "fields.csv")
+			if err := os.WriteFile(path, []byte(tc.csv), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadSchema(path)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got error %v, want %q", err, tc.err)
+				}
+				if len(got) != 0 {
+					t.Errorf("got %v along with error, want no fields", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadSchemaMissingFile(t *testing.T) {
+	got, err := LoadSchema(filepath.Join(t.TempDir(), "fields.csv"))
+	if err != nil || len(got) != 0 {
+		t.Errorf("got %v, %v, want empty schema and no error", got, err)
+	}
+}
+
+func TestScoreboardCustomJSON(t *testing.T) {
+	sb := Scoreboard{P1name: "A", Custom: map[string]string{"pool": "B"}}
+	blob, err := json.Marshal(sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	json.Unmarshal(blob, &fields)
+	if fields["pool"] != "B" || fields["p1name"] != "A" {
+		t.Errorf("custom field not next to built-in ones: %s", blob)
+	}
+
+	// Decoding on top only changes the given fields, and takes numbers.
+	err = json.Unmarshal([]byte(`{"games": 3, "p2name": "C"}`), &sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"pool": "B", "games": "3"}
+	if !reflect.DeepEqual(sb.Custom, want) || sb.P1name != "A" || sb.P2name != "C" {
+		t.Errorf("got %+v, want custom %v", sb, want)
+	}
+
+	err = json.Unmarshal([]byte(`{"pool": {"nested": true}}`), &sb)
+	if err == nil {
+		t.Error("object as custom field: expected error")
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"go.imnhan.com/gorts/ipc"
+)
+
+// A GUI that stops sooner than this after starting counts towards
+// MaxGUIFailures, as it'll most likely keep doing so.
+const GUIStableAfter = 10 * time.Second
+const MaxGUIFailures = 3
+
+// ErrNoTclTk means the GUI can't run at all, so there's no point restarting it.
+var ErrNoTclTk = errors.New("Tcl/Tk not found")
+
+// superviseGUI runs the GUI until the user closes it. If it crashes or gets
+// killed instead, it's restarted, and picks up the scoreboard and settings
+// from serve. If it can't run at all, GORTS carries on headless, so that the
+// overlay keeps working.
+func superviseGUI(
+	tclPath string,
+	serve func(io.Reader, *ipc.Writer),
+	session *ipc.Session,
+) {
+	failures := 0
+	for {
+		started := time.Now()
+		err := runGUI(tclPath, serve, session)
+		if err == nil {
+			return
+		}
+		fmt.Printf("GUI stopped: %s\n", err)
+		if errors.Is(err, ErrNoTclTk) {
+			guiUnavailable(TclInstallHint)
+			return
+		}
+		if time.Since(started) < GUIStableAfter {
+			failures++
+		} else {
+			failures = 0
+		}
+		if failures >= MaxGUIFailures {
+			guiUnavailable(fmt.Sprintf(
+				"GUI stopped %d times in a row, giving up.", failures,
+			))
+			return
+		}
+		println("Restarting GUI...")
+	}
+}
+
+// runGUI starts a Tcl process running the GUI and serves it until it exits.
+// It returns nil if the user closed the GUI, i.e. Tcl exited successfully.
+func runGUI(
+	tclPath string,
+	serve func(io.Reader, *ipc.Writer),
+	session *ipc.Session,
+) error {
+	cmd := exec.Command(tclPath, "-encoding", "utf-8")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return fmt.Errorf("%w at %s: %s", ErrNoTclTk, tclPath, err)
+	}
+
+	// Tcl without Tk starts fine, then fails at "package require Tk".
+	var noTk bool
+	var stderrDone sync.WaitGroup
+	stderrDone.Add(1)
+	go func() {
+		defer stderrDone.Done()
+		errscanner := bufio.NewScanner(stderr)
+		for errscanner.Scan() {
+			errtext := errscanner.Text()
+			fmt.Printf("Tcl: %s\n", errtext)
+			if strings.Contains(errtext, "can't find package Tk") {
+				// Tcl would otherwise keep reading commands without a
+				// window, and never exit for serve to return.
+				noTk = true
+				cmd.Process.Kill()
+			}
+		}
+	}()
+
+	var in io.Reader = stdout
+	var out io.Writer = stdin
+	if session != nil {
+		in = io.TeeReader(stdout, session.Writer(ipc.Incoming))
+		out = io.MultiWriter(stdin, session.Writer(ipc.Outgoing))
+	}
+	gui := ipc.NewWriter(out)
+
+	gui.Command(`source -encoding "utf-8" tcl/main.tcl`)
+	println("Loaded main tcl script.")
+
+	serve(in, gui)
+	println("Tcl process terminated.")
+	stdin.Close()
+
+	// Wait must only be called once the pipes have been read to the end.
+	stderrDone.Wait()
+	err = cmd.Wait()
+	if noTk {
+		return fmt.Errorf("%w: %s has no Tk", ErrNoTclTk, tclPath)
+	}
+	return err
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const HistoryDir = "history"
+
+// DayFormat names history files, and is what the export API takes as date.
+const DayFormat = "2006-01-02"
+
+// History appends every applied scoreboard to a JSON Lines file per day, so
+// past sets can still be looked up and exported after state.json has moved
+// on to the next one.
+type History struct {
+	dir string
+	// Follows the log as it's written, to tell when a set has finished.
+	tracker setTracker
+}
+
+type HistoryEntry struct {
+	Time       time.Time  `json:"time"`
+	Scoreboard Scoreboard `json:"scoreboard"`
+}
+
+// FinishedSet is what a set looked like on stream when it ended.
+type FinishedSet struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Description string    `json:"description"`
+	Subtitle    string    `json:"subtitle"`
+	P1name      string    `json:"p1name"`
+	P1country   string    `json:"p1country"`
+	P1team      string    `json:"p1team"`
+	P1character string    `json:"p1character"`
+	P1score     int       `json:"p1score"`
+	P2name      string    `json:"p2name"`
+	P2country   string    `json:"p2country"`
+	P2team      string    `json:"p2team"`
+	P2character string    `json:"p2character"`
+	P2score     int       `json:"p2score"`
+}
+
+func (s FinishedSet) String() string {
+	return fmt.Sprintf(
+		"%s %d - %d %s", s.P1name, s.P1score, s.P2score, s.P2name,
+	)
+}
+
+// NewHistory picks up where today's log left off, so that a restart in the
+// middle of a set doesn't split it in two.
+func NewHistory(dir string) *History {
+	h := &History{dir: dir}
+	entries, err := h.Load(time.Now())
+	if err != nil {
+		fmt.Printf("Ignoring history: %s\n", err)
+	}
+	for _, e := range entries {
+		h.tracker.add(e)
+	}
+	return h
+}
+
+func (h *History) path(day time.Time) string {
+	return filepath.Join(h.dir, day.Format(DayFormat)+".jsonl")
+}
+
+// Append logs an applied scoreboard. It's called with the State lock held,
+// so entries are always in the order they were applied.
+func (h *History) Append(scoreboard Scoreboard) error {
+	entry := HistoryEntry{Time: time.Now(), Scoreboard: scoreboard}
+	if set, ok := h.tracker.add(entry); ok {
+		fmt.Printf("Set finished: %s\n", set)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("append history: %w", err)
+	}
+	err = os.MkdirAll(h.dir, 0755)
+	if err != nil {
+		return fmt.Errorf("append history: %w", err)
+	}
+	f, err := os.OpenFile(
+		h.path(entry.Time), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644,
+	)
+	if err != nil {
+		return fmt.Errorf("append history: %w", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("append history: %w", err)
+	}
+	return nil
+}
+
+// Load returns every entry logged on day, which is empty if there's none.
+// Lines that can't be parsed, e.g. one cut short by a crash, are skipped.
+func (h *History) Load(day time.Time) ([]HistoryEntry, error) {
+	result := make([]HistoryEntry, 0)
+	f, err := os.Open(h.path(day))
+	if errors.Is(err, fs.ErrNotExist) {
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("load history: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	// Lines hold the whole scoreboard, which can outgrow the default.
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			result = append(result, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("load history: %w", err)
+	}
+	return result, nil
+}
+
+// Sets returns the sets that finished on day, in order. The set still on
+// the scoreboard isn't finished until the scoreboard moves on from it.
+func (h *History) Sets(day time.Time) ([]FinishedSet, error) {
+	entries, err := h.Load(day)
+	if err != nil {
+		return nil, err
+	}
+	var tracker setTracker
+	result := make([]FinishedSet, 0)
+	for _, e := range entries {
+		if set, ok := tracker.add(e); ok {
+			result = append(result, set)
+		}
+	}
+	return result, nil
+}
+
+// Export writes day's finished sets next to its log, as both CSV and JSON.
+// It returns how many sets there were and the paths written to.
+func (h *History) Export(day time.Time) (n int, csvPath, jsonPath string, err error) {
+	sets, err := h.Sets(day)
+	if err != nil {
+		return 0, "", "", err
+	}
+	base := filepath.Join(h.dir, day.Format(DayFormat)+"-sets")
+	csvPath, jsonPath = base+".csv", base+".json"
+
+	err = os.MkdirAll(h.dir, 0755)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("export sets: %w", err)
+	}
+	f, err := os.Create(csvPath)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("export sets: %w", err)
+	}
+	err = WriteSetsCSV(f, sets)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, "", "", fmt.Errorf("export sets: %w", err)
+	}
+
+	blob, err := json.MarshalIndent(sets, "", "    ")
+	if err != nil {
+		return 0, "", "", fmt.Errorf("export sets: %w", err)
+	}
+	err = os.WriteFile(jsonPath, blob, 0644)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("export sets: %w", err)
+	}
+	return len(sets), csvPath, jsonPath, nil
+}
+
+var setsCSVHeader = []string{
+	"start", "end", "description", "subtitle",
+	"p1name", "p1country", "p1team", "p1character", "p1score",
+	"p2name", "p2country", "p2team", "p2character", "p2score",
+}
+
+func WriteSetsCSV(w io.Writer, sets []FinishedSet) error {
+	writer := csv.NewWriter(w)
+	writer.Write(setsCSVHeader)
+	for _, s := range sets {
+		writer.Write([]string{
+			s.Start.Format(time.RFC3339), s.End.Format(time.RFC3339),
+			s.Description, s.Subtitle,
+			s.P1name, s.P1country, s.P1team, s.P1character, strconv.Itoa(s.P1score),
+			s.P2name, s.P2country, s.P2team, s.P2character, strconv.Itoa(s.P2score),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// setTracker tells when a set has finished: either different players are
+// now on the scoreboard, or the same players had their scores reset to 0-0
+// (e.g. grand final reset, or a rematch). Sets in which nobody ever scored
+// weren't actually played, so they don't count.
+type setTracker struct {
+	current *FinishedSet
+}
+
+// add returns the set that entry's scoreboard has just moved on from, if any.
+func (t *setTracker) add(entry HistoryEntry) (finished FinishedSet, ok bool) {
+	sb := entry.Scoreboard
+	if t.current != nil && t.isNewSet(sb) {
+		finished, ok = *t.current, t.current.P1score+t.current.P2score > 0
+		t.current = nil
+	}
+	if t.current == nil {
+		t.current = &FinishedSet{Start: entry.Time, End: entry.Time}
+	}
+
+	c := t.current
+	// Swapping sides doesn't change the total, so only actual games
+	// (or corrections) count as the set going on.
+	if sb.P1score+sb.P2score != c.P1score+c.P2score {
+		c.End = entry.Time
+	}
+	c.Description = sb.Description
+	c.Subtitle = sb.Subtitle
+	c.P1name, c.P1country, c.P1team = sb.P1name, sb.P1country, sb.P1team
+	c.P1character, c.P1score = sb.P1character, sb.P1score
+	c.P2name, c.P2country, c.P2team = sb.P2name, sb.P2country, sb.P2team
+	c.P2character, c.P2score = sb.P2character, sb.P2score
+	return finished, ok
+}
+
+func (t *setTracker) isNewSet(sb Scoreboard) bool {
+	c := t.current
+	samePlayers := (sb.P1name == c.P1name && sb.P2name == c.P2name) ||
+		(sb.P1name == c.P2name && sb.P2name == c.P1name)
+	if !samePlayers {
+		return true
+	}
+	return sb.P1score == 0 && sb.P2score == 0 && c.P1score+c.P2score > 0
+}
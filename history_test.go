@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetTracker(t *testing.T) {
+	sb := func(p1 string, s1 int, p2 string, s2 int) Scoreboard {
+		return Scoreboard{P1name: p1, P1score: s1, P2name: p2, P2score: s2}
+	}
+	for _, tc := range []struct {
+		name    string
+		applied []Scoreboard
+		// Finished sets, as FinishedSet.String.
+		want []string
+	}{
+		{
+			name:    "still on the scoreboard",
+			applied: []Scoreboard{sb("A", 0, "B", 0), sb("A", 1, "B", 0)},
+			want:    []string{},
+		},
+		{
+			name: "next players",
+			applied: []Scoreboard{
+				sb("A", 0, "B", 0), sb("A", 2, "B", 1), sb("C", 0, "D", 0),
+			},
+			want: []string{"A 2 - 1 B"},
+		},
+		{
+			name: "swapping sides is the same set",
+			applied: []Scoreboard{
+				sb("A", 1, "B", 0), sb("B", 0, "A", 1), sb("B", 0, "A", 2),
+				sb("C", 0, "D", 0),
+			},
+			want: []string{"B 0 - 2 A"},
+		},
+		{
+			name: "grand final reset",
+			applied: []Scoreboard{
+				sb("A", 3, "B", 2), sb("A", 0, "B", 0), sb("A", 3, "B", 1),
+				sb("C", 0, "D", 0),
+			},
+			want: []string{"A 3 - 2 B", "A 3 - 1 B"},
+		},
+		{
+			name: "nobody scored",
+			applied: []Scoreboard{
+				sb("A", 0, "B", 0), sb("C", 0, "D", 0), sb("E", 0, "F", 0),
+			},
+			want: []string{},
+		},
+		{
+			name: "wipe to empty players",
+			applied: []Scoreboard{
+				sb("A", 2, "B", 0), sb("", 0, "", 0), sb("", 0, "", 0),
+			},
+			want: []string{"A 2 - 0 B"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var tracker setTracker
+			got := make([]string, 0)
+			start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+			for i, s := range tc.applied {
+				entry := HistoryEntry{
+					Time:       start.Add(time.Duration(i) * time.Minute),
+					Scoreboard: s,
+				}
+				if set, ok := tracker.add(entry); ok {
+					got = append(got, set.String())
+				}
+			}
+			if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+				t.Errorf("finished %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSetTrackerTimes(t *testing.T) {
+	var tracker setTracker
+	at := func(minute int, s Scoreboard) (FinishedSet, bool) {
+		return tracker.add(HistoryEntry{
+			Time:       time.Date(2026, 1, 1, 12, minute, 0, 0, time.UTC),
+			Scoreboard: s,
+		})
+	}
+	at(0, Scoreboard{P1name: "A", P2name: "B"})
+	at(5, Scoreboard{P1name: "A", P1score: 1, P2name: "B"})
+	// Fixing a typo after the last game doesn't extend the set.
+	at(9, Scoreboard{P1name: "A", P1score: 1, P2name: "B", Subtitle: "WF"})
+	set, ok := at(20, Scoreboard{P1name: "C", P2name: "D"})
+	if !ok {
+		t.Fatal("set not finished")
+	}
+	if set.Start.Minute() != 0 || set.End.Minute() != 5 {
+		t.Errorf("set from :%02d to :%02d, want :00 to :05", set.Start.Minute(), set.End.Minute())
+	}
+	if set.Subtitle != "WF" {
+		t.Errorf("subtitle = %q, want the last one applied", set.Subtitle)
+	}
+}
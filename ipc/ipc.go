@@ -1,20 +1,80 @@
+// Package ipc talks to the Tcl GUI over its stdin/stdout, in either of two
+// protocols that can be mixed on the same stream:
+//
+// The legacy protocol is line-based: a request is "method N" followed by N
+// lines, one per argument, and a response is a line with the number of
+// values followed by one line per value. Values can't contain newlines, so
+// Respond replaces them.
+//
+// Version 1 frames every message as a JSON object on a single line, which
+// JSON strings can always be encoded into:
+//
+//	--> {"v":1,"method":"applyscoreboard","params":{"p1name":"Daigo"}}
+//	<-- {"v":1,"result":{"p1name":"Daigo"}}
+//
+// Requests may have positional "args", named "params", or both. Responses
+// have "values", a "result" object, or an "error". Lines starting with "{"
+// are JSON, so legacy method names must not.
+//
+// Requests with an "id" are answered asynchronously, whenever they're done,
+// so the GUI may send others in the meantime: see Writer.RespondAsync.
+//
+// Any request can fail, in which case it's answered with an "error" in JSON,
+// or with the two values "err" and the message in the legacy protocol: see
+// Writer.RespondError.
 package ipc
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"strconv"
 	"strings"
+	"sync"
 )
 
+// Version of the JSON protocol.
+const Version = 1
+
 type Request struct {
+	// 0 for legacy requests, which only have Args.
+	Version int
+	// Only set for requests that expect an async response.
+	ID     string
 	Method string
 	Args   []string
+	Params map[string]string
+	// Set if a JSON request couldn't be decoded, in which case it should be
+	// responded to with the error.
+	Err error
+}
+
+// Response is a response in the JSON protocol.
+type Response struct {
+	V int `json:"v"`
+	// ID of the request, for async responses.
+	ID     string   `json:"id,omitempty"`
+	Values []string `json:"values,omitempty"`
+	Result any      `json:"result,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+type message struct {
+	V      int               `json:"v"`
+	ID     string            `json:"id"`
+	Method string            `json:"method"`
+	Args   []string          `json:"args"`
+	Params map[string]string `json:"params"`
 }
 
+// Debug prints the start of every line of traffic to stdout.
+var Debug = true
+
 func debug(prefix string, msg string) {
+	if !Debug {
+		return
+	}
 	out := prefix + " " + msg
 	if len(out) > 35 {
 		out = out[:35] + "[...]"
@@ -24,23 +84,35 @@ func debug(prefix string, msg string) {
 
 func IncomingRequests(r io.Reader) chan Request {
 	scanner := bufio.NewScanner(r)
+	// JSON requests come in a single line, which can be long.
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 	ch := make(chan Request)
 	next := func() string {
 		scanner.Scan()
 		v := scanner.Text()
-		debug("-->", v)
+		debug(Incoming, v)
 		return v
 	}
 
 	go func() {
 		for scanner.Scan() {
 			line := scanner.Text()
-			debug("-->", line)
-			request := strings.SplitN(line, " ", 2)
-			method := request[0]
-			numArgs, err := strconv.Atoi(request[1])
-			if err != nil {
-				panic(err)
+			debug(Incoming, line)
+			if strings.HasPrefix(line, "{") {
+				ch <- decodeRequest(line)
+				continue
+			}
+
+			method, count, _ := strings.Cut(line, " ")
+			numArgs, err := strconv.Atoi(count)
+			if err != nil || numArgs < 0 {
+				// There's no telling how many lines to skip, so all we
+				// can do is answer and carry on with the next line.
+				ch <- Request{
+					Method: method,
+					Err:    fmt.Errorf("malformed request header: %q", line),
+				}
+				continue
 			}
 			args := make([]string, numArgs)
 			for i := 0; i < numArgs; i++ {
@@ -49,8 +121,10 @@ func IncomingRequests(r io.Reader) chan Request {
 
 			ch <- Request{Method: method, Args: args}
 		}
+		// E.g. a line too long to be a request. Either way there's no
+		// getting back in sync, so treat it like the GUI going away.
 		if err := scanner.Err(); err != nil {
-			log.Fatal(err)
+			fmt.Printf("Error reading requests: %s\n", err)
 		}
 
 		close(ch)
@@ -59,9 +133,45 @@ func IncomingRequests(r io.Reader) chan Request {
 	return ch
 }
 
+func decodeRequest(line string) Request {
+	var msg message
+	err := json.Unmarshal([]byte(line), &msg)
+	if err != nil {
+		return Request{
+			Version: Version,
+			Err:     fmt.Errorf("malformed request: %w", err),
+		}
+	}
+	if msg.V != Version {
+		return Request{
+			Version: Version,
+			ID:      msg.ID,
+			Method:  msg.Method,
+			Err: fmt.Errorf(
+				"unsupported protocol version %d, want %d", msg.V, Version,
+			),
+		}
+	}
+	if msg.Args == nil {
+		msg.Args = make([]string, 0)
+	}
+	if msg.Params == nil {
+		msg.Params = make(map[string]string)
+	}
+	return Request{
+		Version: msg.V,
+		ID:      msg.ID,
+		Method:  msg.Method,
+		Args:    msg.Args,
+		Params:  msg.Params,
+	}
+}
+
+// Respond writes values in the legacy protocol. Newlines in a value would be
+// read as the start of the next value or command, so they're replaced.
 func Respond(w io.Writer, values []string) {
 	numValues := strconv.Itoa(len(values))
-	debug("<--", numValues)
+	debug(Outgoing, numValues)
 	fmt.Fprintln(w, numValues)
 	for i, val := range values {
 		// Only print debug message for the first 10 items
@@ -72,9 +182,89 @@ func Respond(w io.Writer, values []string) {
 			} else if i == 10 {
 				msg = "[...]"
 			}
-			debug("<--", msg)
+			debug(Outgoing, msg)
 		}
 
-		fmt.Fprintln(w, val)
+		fmt.Fprintln(w, oneLine(val))
 	}
 }
+
+var newlines = strings.NewReplacer("\r\n", "; ", "\n", "; ", "\r", "; ")
+
+func oneLine(s string) string {
+	return newlines.Replace(s)
+}
+
+// RespondJSON writes resp as a single line, in the current Version.
+func RespondJSON(w io.Writer, resp Response) {
+	resp.V = Version
+	var buf strings.Builder
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(resp)
+	if err != nil {
+		// Only possible with a Result that can't be encoded, which is a
+		// bug on our side, but the GUI is waiting for a response anyway.
+		buf.Reset()
+		encoder.Encode(Response{V: Version, Error: err.Error()})
+	}
+	line := buf.String()
+	debug(Outgoing, strings.TrimSuffix(line, "\n"))
+	io.WriteString(w, line)
+}
+
+// Writer serializes writes to the Tcl process, because its stdin is shared by
+// the request loop and by goroutines that push commands to the GUI.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (w *Writer) Respond(values []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	Respond(w.w, values)
+}
+
+func (w *Writer) RespondJSON(resp Response) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	RespondJSON(w.w, resp)
+}
+
+// RespondAsync answers a request that the GUI isn't waiting on, and may
+// have sent others after. It's sent as the ipc_receive command, which reads
+// resp from the next line and hands it to the request's callback.
+func (w *Writer) RespondAsync(resp Response) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	debug(Outgoing, "ipc_receive")
+	fmt.Fprintln(w.w, "ipc_receive")
+	RespondJSON(w.w, resp)
+}
+
+// RespondError answers req with err, in whichever form it expects.
+func (w *Writer) RespondError(req Request, err error) {
+	switch {
+	case req.ID != "":
+		w.RespondAsync(Response{ID: req.ID, Error: err.Error()})
+	case req.Version > 0:
+		w.RespondJSON(Response{Error: err.Error()})
+	default:
+		w.Respond([]string{"err", err.Error()})
+	}
+}
+
+// Command sends a line of tcl code for the GUI to evaluate. It may arrive
+// while the GUI is waiting for a response, in which case the GUI defers it
+// until the response has been read.
+func (w *Writer) Command(cmd string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	debug(Outgoing, cmd)
+	fmt.Fprintln(w.w, cmd)
+}
@@ -0,0 +1,150 @@
+package ipc
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readAll(input string) []Request {
+	result := make([]Request, 0)
+	for req := range IncomingRequests(strings.NewReader(input)) {
+		result = append(result, req)
+	}
+	return result
+}
+
+func TestIncomingRequests(t *testing.T) {
+	Debug = false
+	got := readAll(strings.Join([]string{
+		"getscoreboard 0",
+		`{"v":1,"method":"applyscoreboard","params":{"p1name":"Daigo"}}`,
+		"searchplayers 1",
+		"{not json, but an argument",
+		`{"v":1,"id":"7","method":"fetchplayers","args":["startgg","tok"]}`,
+	}, "\n") + "\n")
+	want := []Request{
+		{Method: "getscoreboard", Args: []string{}},
+		{
+			Version: 1, Method: "applyscoreboard", Args: []string{},
+			Params: map[string]string{"p1name": "Daigo"},
+		},
+		{Method: "searchplayers", Args: []string{"{not json, but an argument"}},
+		{
+			Version: 1, ID: "7", Method: "fetchplayers",
+			Args: []string{"startgg", "tok"}, Params: map[string]string{},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestIncomingRequestsErrors(t *testing.T) {
+	Debug = false
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  Request
+		err   string
+	}{
+		{
+			name:  "truncated json",
+			input: `{"v":1,"method":"getscoreboard"`,
+			want:  Request{Version: 1},
+			err:   "malformed request",
+		},
+		{
+			name:  "json of the wrong type",
+			input: `{"v":1,"method":"applyscoreboard","params":{"p1score":2}}`,
+			want:  Request{Version: 1},
+			err:   "malformed request",
+		},
+		{
+			name:  "unsupported version",
+			input: `{"v":2,"id":"3","method":"getscoreboard"}`,
+			want:  Request{Version: 1, ID: "3", Method: "getscoreboard"},
+			err:   "unsupported protocol version 2, want 1",
+		},
+		{
+			name:  "missing version",
+			input: `{"method":"getscoreboard"}`,
+			want:  Request{Version: 1, Method: "getscoreboard"},
+			err:   "unsupported protocol version 0",
+		},
+		{
+			name:  "header without count",
+			input: "getscoreboard",
+			want:  Request{Method: "getscoreboard"},
+			err:   "malformed request header",
+		},
+		{
+			name:  "negative count",
+			input: "getscoreboard -1",
+			want:  Request{Method: "getscoreboard"},
+			err:   "malformed request header",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Requests after a bad one must still get through.
+			got := readAll(tc.input + "\ngetwebport 0\n")
+			if len(got) != 2 {
+				t.Fatalf("got %d requests, want 2: %+v", len(got), got)
+			}
+			req := got[0]
+			if req.Err == nil || !strings.Contains(req.Err.Error(), tc.err) {
+				t.Errorf("got error %v, want %q", req.Err, tc.err)
+			}
+			req.Err = nil
+			if !reflect.DeepEqual(req, tc.want) {
+				t.Errorf("got %+v, want %+v", req, tc.want)
+			}
+			if got[1].Method != "getwebport" || got[1].Err != nil {
+				t.Errorf("next request = %+v, want getwebport", got[1])
+			}
+		})
+	}
+}
+
+func TestRespondError(t *testing.T) {
+	Debug = false
+	err := errors.New("no\nway")
+	for _, tc := range []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{"legacy", Request{Method: "undo"}, "2\nerr\nno; way\n"},
+		{"json", Request{Version: 1}, `{"v":1,"error":"no\nway"}` + "\n"},
+		{
+			"async", Request{Version: 1, ID: "4"},
+			"ipc_receive\n" + `{"v":1,"id":"4","error":"no\nway"}` + "\n",
+		},
+	} {
+		var buf strings.Builder
+		NewWriter(&buf).RespondError(tc.req, err)
+		if buf.String() != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, buf.String(), tc.want)
+		}
+	}
+}
+
+func TestRespondMultiLineValue(t *testing.T) {
+	Debug = false
+	var buf strings.Builder
+	Respond(&buf, []string{"ok", "A\nexec calc.exe;\r\nB\r", "next"})
+	want := "3\nok\nA; exec calc.exe;; B; \nnext\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRespondJSONUnencodable(t *testing.T) {
+	Debug = false
+	var buf strings.Builder
+	RespondJSON(&buf, Response{Result: func() {}})
+	if !strings.HasPrefix(buf.String(), `{"v":1,"error":"json: unsupported type`) {
+		t.Errorf("got %q, want an error response", buf.String())
+	}
+}
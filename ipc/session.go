@@ -0,0 +1,128 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Directions of recorded lines, as shown by debug.
+const (
+	Incoming = "-->"
+	Outgoing = "<--"
+	// Not IPC traffic, but the scoreboard when recording started, in JSON,
+	// so that a replay can start from it.
+	Snapshot = "==="
+)
+
+// Entry is a line of a recorded session.
+type Entry struct {
+	Time time.Time `json:"t"`
+	Dir  string    `json:"dir"`
+	Line string    `json:"line"`
+}
+
+// Session records IPC traffic in both directions, with timestamps, as one
+// JSON Entry per line. Recording never fails as far as the GUI is concerned:
+// write errors are printed and the rest of the session is dropped.
+type Session struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+func NewSession(w io.Writer) *Session {
+	return &Session{enc: json.NewEncoder(w)}
+}
+
+func (s *Session) Record(dir string, line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	s.err = s.enc.Encode(Entry{Time: time.Now(), Dir: dir, Line: line})
+	if s.err != nil {
+		fmt.Printf("Stopped recording session: %s\n", s.err)
+	}
+}
+
+// Writer records every line written to it in direction dir, so that it can
+// be teed into.
+func (s *Session) Writer(dir string) io.Writer {
+	return NewLineWriter(func(line string) { s.Record(dir, line) })
+}
+
+// LineWriter calls fn with every complete line written to it, without its
+// newline. Writes never fail.
+type LineWriter struct {
+	mu  sync.Mutex
+	fn  func(line string)
+	buf []byte
+}
+
+func NewLineWriter(fn func(line string)) *LineWriter {
+	return &LineWriter{fn: fn}
+}
+
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, b := range p {
+		if b == '\n' {
+			w.fn(string(w.buf))
+			w.buf = w.buf[:0]
+			continue
+		}
+		w.buf = append(w.buf, b)
+	}
+	return len(p), nil
+}
+
+func ReadSession(r io.Reader) ([]Entry, error) {
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return entries, fmt.Errorf("read session, line %d: %w", n, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, fmt.Errorf("read session: %w", err)
+	}
+	return entries, nil
+}
+
+// Replay returns a reader of the Incoming lines of entries, as if the GUI
+// was sending them again. They're paced as they were recorded, except that
+// no gap is longer than maxGap, which is also how long the reader stays open
+// after the last line, for responses to anything still in flight.
+func Replay(entries []Entry, maxGap time.Duration) io.Reader {
+	r, w := io.Pipe()
+	go func() {
+		var last time.Time
+		for _, e := range entries {
+			if e.Dir != Incoming {
+				continue
+			}
+			if !last.IsZero() {
+				gap := e.Time.Sub(last)
+				if gap > maxGap {
+					gap = maxGap
+				}
+				time.Sleep(gap)
+			}
+			last = e.Time
+			fmt.Fprintln(w, e.Line)
+		}
+		time.Sleep(maxGap)
+		w.Close()
+	}()
+	return r
+}
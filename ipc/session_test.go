@@ -0,0 +1,106 @@
+package ipc
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineWriter(t *testing.T) {
+	lines := make([]string, 0)
+	w := NewLineWriter(func(line string) { lines = append(lines, line) })
+	for _, chunk := range []string{"get", "scoreboard 0\n1\nDai", "go\n", "\n", "partial"} {
+		n, err := io.WriteString(w, chunk)
+		if n != len(chunk) || err != nil {
+			t.Fatalf("write %q = %d, %v", chunk, n, err)
+		}
+	}
+	want := []string{"getscoreboard 0", "1", "Daigo", ""}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("got %q, want %q", lines, want)
+	}
+}
+
+func TestSessionRoundTrip(t *testing.T) {
+	var buf strings.Builder
+	session := NewSession(&buf)
+	session.Record(Snapshot, `{"p1name":"A"}`)
+	io.WriteString(session.Writer(Incoming), "searchplayers 1\nDai\n")
+	io.WriteString(session.Writer(Outgoing), "1\nDaigo\n")
+
+	entries, err := ReadSession(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Time.IsZero() {
+			t.Errorf("entry without time: %+v", e)
+		}
+		got = append(got, e.Dir+" "+e.Line)
+	}
+	want := []string{
+		`=== {"p1name":"A"}`,
+		"--> searchplayers 1", "--> Dai",
+		"<-- 1", "<-- Daigo",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReadSessionErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		// Entries read before the error.
+		entries int
+		err     string
+	}{
+		{"empty", "", 0, ""},
+		{
+			"truncated line",
+			`{"t":"2026-01-01T10:00:00Z","dir":"-->","line":"getwebport 0"}` +
+				"\n" + `{"t":"2026-01-01T10:00:01Z","dir":"-->","li`,
+			1, "line 2",
+		},
+		{"not json", "getwebport 0\n", 0, "line 1"},
+		{"bad time", `{"t":"yesterday","dir":"-->","line":"x"}`, 0, "line 1"},
+	} {
+		entries, err := ReadSession(strings.NewReader(tc.input))
+		if len(entries) != tc.entries {
+			t.Errorf("%s: got %d entries, want %d", tc.name, len(entries), tc.entries)
+		}
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("%s: got error %v, want %q", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{start, Snapshot, "{}"},
+		{start, Incoming, "getwebport 0"},
+		{start, Outgoing, "1"},
+		{start, Outgoing, "1337"},
+		// An hour later, but replays don't wait that long.
+		{start.Add(time.Hour), Incoming, "getscoreboard 0"},
+	}
+	began := time.Now()
+	blob, err := io.ReadAll(Replay(entries, 10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(blob) != "getwebport 0\ngetscoreboard 0\n" {
+		t.Errorf("got %q, want only incoming lines", blob)
+	}
+	if elapsed := time.Since(began); elapsed > time.Second {
+		t.Errorf("replay took %s, gaps should be capped", elapsed)
+	}
+}
@@ -1,22 +1,32 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	_ "embed"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"os"
-	"os/exec"
+	"os/signal"
+	"runtime/debug"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"go.imnhan.com/gorts/bracket"
+	"go.imnhan.com/gorts/challonge"
 	"go.imnhan.com/gorts/ipc"
+	"go.imnhan.com/gorts/offline"
 	"go.imnhan.com/gorts/players"
 	"go.imnhan.com/gorts/startgg"
+	"go.imnhan.com/gorts/tournament"
 )
 
 const WebPort = "1337"
@@ -26,401 +36,1190 @@ const BracketFile = WebDir + "/bracket.json"
 const PlayersFile = "players.csv"
 const CharactersFile = "characters.csv"
 const StagesFile = "stages.csv"
-const StartggFile = "creds-startgg"
+const StartggFile = "startgg.json"
+const LegacyStartggFile = "creds-startgg"
+const ChallongeFile = "challonge.json"
+const OfflineFile = "offline.json"
+const PresetsFile = "presets.json"
+const FieldsFile = "fields.csv"
+const RoundNamesFile = "rounds.csv"
 
 func main() {
-	// No need to wait on the http server,
-	// just let it die when the GUI is closed.
-	go func() {
-		println("Serving scoreboard at http://localhost:" + WebPort)
-		fs := http.FileServer(http.Dir(WebDir))
-		http.Handle("/", fs)
-		err := http.ListenAndServe("127.0.0.1:"+WebPort, nil)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
-
 	tclPathPtr := flag.String("tcl", DefaultTclPath, "Path to tclsh executable")
+	hostPtr := flag.String(
+		"host", "127.0.0.1",
+		"Address to serve the overlay and HTTP API on. "+
+			"Use 0.0.0.0 to let other machines on the network control GORTS.",
+	)
+	tokenPtr := flag.String(
+		"token", "",
+		"Require this token for the HTTP API, as an Authorization: Bearer "+
+			"header or ?token= query. Recommended with -host 0.0.0.0.",
+	)
+	nameFormatPtr := flag.String(
+		"teamname", string(players.MemberNames),
+		"How to display doubles and 2v2 teams: "+
+			"names (A / B), team (Team) or team+names (Team (A / B)). "+
+			"Teams without a name always show their members' names.",
+	)
+	headlessPtr := flag.Bool(
+		"headless", false,
+		"Run without the Tcl/Tk GUI, only serving the overlay and HTTP API "+
+			"until interrupted.",
+	)
+	recordPtr := flag.String(
+		"record", "",
+		"Record all traffic between GORTS and the GUI to this session file, "+
+			"for debugging. It includes API keys, so mind who you share it with.",
+	)
+	replayPtr := flag.String(
+		"replay", "",
+		"Replay a session file recorded with -record, without the GUI, "+
+			"in a copy of the current directory's data. Requests to "+
+			"tournament sites fail instead of being made again.",
+	)
 	flag.Parse()
 
-	startGUI(*tclPathPtr)
-}
-
-func startGUI(tclPath string) {
-	cmd := exec.Command(tclPath, "-encoding", "utf-8")
-	stdout, err := cmd.StdoutPipe()
+	nameFormat, err := players.ParseNameFormat(*nameFormatPtr)
 	if err != nil {
-		panic(err)
+		fmt.Println(err)
+		os.Exit(2)
 	}
 
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		panic(err)
+	var replay []ipc.Entry
+	if *replayPtr != "" {
+		replay, err = enterReplay(*replayPtr)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	}
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		panic(err)
-	}
+	// Nothing that fails to load is worth refusing to start over.
+	var loadErrs loadErrors
+	schema, err := LoadSchema(FieldsFile)
+	loadErrs.ignore("custom fields", err)
+	scoreboard, err := initScoreboard()
+	loadErrs.ignore("saved scoreboard", err)
+	state := NewState(scoreboard, nameFormat, schema, NewHistory(HistoryDir))
+	catalog, err := LoadCatalog()
+	loadErrs.ignore("some of the players, characters and stages", err)
+	fmt.Printf(
+		"Loaded %d players, %d characters, %d stages.\n",
+		catalog.NumPlayers(), len(catalog.Characters()), len(catalog.Stages()),
+	)
 
-	err = cmd.Start()
-	if err != nil {
-		panic(err)
+	if replay != nil {
+		replaySession(replay, state, catalog, nameFormat, loadErrs)
+		return
 	}
 
-	go func() {
-		errscanner := bufio.NewScanner(stderr)
-		for errscanner.Scan() {
-			errtext := errscanner.Text()
-			fmt.Printf("XXX %s\n", errtext)
+	var session *ipc.Session
+	if *recordPtr != "" {
+		f, err := os.Create(*recordPtr)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
-	}()
-
-	fmt.Fprintln(stdin, `source -encoding "utf-8" tcl/main.tcl`)
-	println("Loaded main tcl script.")
+		defer f.Close()
+		session = ipc.NewSession(f)
+		blob, _ := json.Marshal(state.Scoreboard())
+		session.Record(ipc.Snapshot, string(blob))
+		fmt.Printf("Recording session to %s\n", *recordPtr)
+	}
 
-	allplayers := players.FromFile(PlayersFile)
-	scoreboard := initScoreboard()
-	startggInputs := startgg.LoadInputs(StartggFile)
-	characters := FromCSVFile(CharactersFile)
-	stages := FromCSVFile(StagesFile)
+	server := startWebServer(*hostPtr+":"+WebPort, state, catalog, *tokenPtr)
 
-	fmt.Fprintln(stdin, "initialize")
+	if *headlessPtr {
+		waitForSignal()
+	} else {
+		serve := newGUIServer(state, catalog, nameFormat, loadErrs, guiSites{
+			startggTokenFile: startgg.DefaultTokenPath(),
+			challongeKeyFile: challonge.DefaultKeyPath(),
+		})
+		superviseGUI(*tclPathPtr, serve, session)
+	}
 
-	respond := func(values ...string) {
-		ipc.Respond(stdin, values)
+	server.Shutdown()
+	// Every apply is already written to disk, but better safe than sorry.
+	scoreboard = state.Scoreboard()
+	if err := scoreboard.Write(); err != nil {
+		fmt.Printf("Error: %s\n", err)
 	}
+	println("Bye.")
+}
 
-	for req := range ipc.IncomingRequests(stdout) {
-		switch req.Method {
+func waitForSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	println("Running headless. Press Ctrl+C to quit.")
+	sig := <-signals
+	println("Received", sig.String())
+}
 
-		case "forcefocus":
-			err := forceFocus(req.Args[0])
-			if err != nil {
-				fmt.Printf("forcefocus: %s\n", err)
+// guiSites is how the GUI server reaches tournament sites.
+type guiSites struct {
+	// Where credentials are kept, see startgg.DefaultTokenPath.
+	startggTokenFile string
+	challongeKeyFile string
+	// Makes every request to a tournament site, if not the default.
+	transport http.RoundTripper
+}
+
+// newGUIServer loads the GUI's settings, and returns a func that answers a
+// GUI's requests from r until it closes. Responses and commands go to gui,
+// starting with "initialize". Everything the GUI changes is kept in memory
+// between calls, so that a restarted GUI picks up where the last one left
+// off, see superviseGUI.
+func newGUIServer(
+	state *State,
+	catalog *Catalog,
+	nameFormat players.NameFormat,
+	loadErrs loadErrors,
+	sites guiSites,
+) func(r io.Reader, gui *ipc.Writer) {
+	startggTokenFile := sites.startggTokenFile
+	startggInputs, err := startgg.LoadInputs(
+		StartggFile, startggTokenFile, LegacyStartggFile,
+	)
+	loadErrs.ignore("start.gg settings", err)
+	saveStartgg := func() error {
+		err := startggInputs.Write(StartggFile, startggTokenFile)
+		if err != nil {
+			return fmt.Errorf("couldn't save start.gg settings: %w", err)
+		}
+		return nil
+	}
+	challongeKeyFile := sites.challongeKeyFile
+	challongeInputs, err := challonge.LoadInputs(ChallongeFile, challongeKeyFile)
+	loadErrs.ignore("Challonge settings", err)
+	saveChallonge := func() error {
+		err := challongeInputs.Write(ChallongeFile, challongeKeyFile)
+		if err != nil {
+			return fmt.Errorf("couldn't save Challonge settings: %w", err)
+		}
+		return nil
+	}
+	roundNames, err := tournament.LoadRoundNames(RoundNamesFile)
+	loadErrs.ignore("round names", err)
+	for name, mapped := range startggInputs.RoundNames {
+		roundNames[name] = mapped
+	}
+	// Most recently fetched matches of each provider, and the match that was
+	// last loaded into the GUI, which is what we report results for.
+	matches := make(map[string][]tournament.Match)
+	var loaded tournament.Match
+	var loadedProvider string
+	// Shared so that all requests count towards the same rate limit.
+	client := startgg.NewClient(startggInputs.Token)
+	challongeClient := challonge.NewClient(challongeInputs.APIKey)
+	if sites.transport != nil {
+		client.HTTPClient.Transport = sites.transport
+		challongeClient.HTTPClient.Transport = sites.transport
+	}
+	ctx := context.Background()
+
+	// useProvider updates a provider's settings from the GUI, which sends its
+	// name followed by everything on its tab, see providerargs in tcl.
+	useProvider := func(args []string) (string, tournament.Provider) {
+		switch name := args[0]; name {
+		case "offline":
+			return name, &offline.Provider{Path: OfflineFile}
+		case "challonge":
+			challongeInputs.APIKey = args[1]
+			challongeInputs.Tournament = args[2]
+			challongeClient.SetAPIKey(challongeInputs.APIKey)
+			return name, &challonge.Provider{
+				Client:     challongeClient,
+				Tournament: challongeInputs.Tournament,
 			}
-			respond("ok")
-
-		case "getstartgg":
-			respond(startggInputs.Token, startggInputs.Slug)
-
-		case "getwebport":
-			respond(WebPort)
-
-		case "getcountrycodes":
-			respond(startgg.CountryCodes...)
-
-		case "getscoreboard":
-			// TODO: there must be a more... civilized way.
-			respond(
-				scoreboard.Description,
-				scoreboard.Subtitle,
-				scoreboard.P1name,
-				scoreboard.P1country,
-				strconv.Itoa(scoreboard.P1score),
-				scoreboard.P1team,
-				scoreboard.P2name,
-				scoreboard.P2country,
-				strconv.Itoa(scoreboard.P2score),
-				scoreboard.P2team,
-			)
-
-		case "applyscoreboard":
-			scoreboard.Description = req.Args[0]
-			scoreboard.Subtitle = req.Args[1]
-			scoreboard.Stage = req.Args[2]
-			scoreboard.P1name = req.Args[3]
-			scoreboard.P1country = req.Args[4]
-			scoreboard.P1score, _ = strconv.Atoi(req.Args[5])
-			scoreboard.P1team = req.Args[6]
-			scoreboard.P1character = req.Args[7]
-			scoreboard.P2name = req.Args[8]
-			scoreboard.P2country = req.Args[9]
-			scoreboard.P2score, _ = strconv.Atoi(req.Args[10])
-			scoreboard.P2team = req.Args[11]
-			scoreboard.P2character = req.Args[12]
-			scoreboard.C1Title = req.Args[13]
-			scoreboard.C1Subtitle = req.Args[14]
-			scoreboard.C2Title = req.Args[15]
-			scoreboard.C2Subtitle = req.Args[16]
-			scoreboard.Write()
-			respond()
-
-		case "searchplayers":
-			query := req.Args[0]
-			var names []string
-
-			if query == "" {
-				for _, p := range allplayers {
-					names = append(names, p.Name)
-				}
-				respond(names...)
-				break
+		default:
+			startggInputs.Token = args[1]
+			startggInputs.Slug = args[2]
+			startggInputs.PhaseGroupId = args[3]
+			startggInputs.EventIds = args[5:]
+			client.SetToken(startggInputs.Token)
+			return "startgg", &startgg.Provider{
+				Client:           client,
+				Inputs:           startggInputs,
+				ReportCharacters: args[4] == "1",
 			}
+		}
+	}
+	saveProvider := func(name string) error {
+		switch name {
+		case "challonge":
+			return saveChallonge()
+		case "startgg":
+			return saveStartgg()
+		}
+		return nil
+	}
+
+	schema := state.Schema()
+	presets, err := LoadPresets(PresetsFile)
+	loadErrs.ignore("presets", err)
+
+	return func(r io.Reader, gui *ipc.Writer) {
+		gui.Command("initialize")
 
-			for _, p := range allplayers {
-				if p.MatchesName(query) {
-					names = append(names, p.Name)
+		// Scoreboard changes may come from elsewhere, e.g. the HTTP API,
+		// so let the GUI know to reload.
+		updates, unsubscribe := state.Subscribe()
+		defer unsubscribe()
+		<-updates // skip current value, which the GUI loads on initialize
+		guiDone := make(chan struct{})
+		defer close(guiDone)
+		go func() {
+			for {
+				select {
+				case <-updates:
+					gui.Command("scoreboardchanged")
+				case <-guiDone:
+					return
 				}
 			}
-			respond(names...)
-		case "loadcharacters":
-			var result []string
-			for _, p := range characters {
-				result = append(result, p)
-			}
-			respond(result...)
-			break
+		}()
 
-		case "loadstages":
-			var result []string
-			for _, p := range stages {
-				result = append(result, p)
-			}
-			respond(result...)
-			break
-		case "fetchplayers":
-			startggInputs.Token = req.Args[0]
-			startggInputs.Slug = req.Args[1]
-			ps, err := startgg.FetchPlayers(startggInputs)
-			fmt.Fprintln(stdin, "fetchplayers__resp")
-			if err != nil {
-				respond("err", fmt.Sprintf("Error: %s", err))
-				break
-			}
-			allplayers = ps
-			// TODO: show write errors to user instead of ignoring
-			startggInputs.Write(StartggFile)
-			players.Write(PlayersFile, allplayers)
-			respond("ok", fmt.Sprintf("Successfully fetched %d players.", len(allplayers)))
-
-		case "fetchlateststreamqueue":
-			startggInputs.Token = req.Args[0]
-			startggInputs.Slug = req.Args[1]
-			playerOne, playerTwo, err := startgg.FetchLatestStreamQueue(startggInputs)
-			fmt.Fprintln(stdin, "getstreamqueue__resp")
+		respond := func(values ...string) {
+			gui.Respond(values)
+		}
+		// For requests in the JSON protocol, i.e. req.Version > 0
+		respondResult := func(result any) {
+			gui.RespondJSON(ipc.Response{Result: result})
+		}
+		// For any request, in the form it expects: see ipc.Writer.RespondError
+		respondError := func(req ipc.Request, err error) {
+			gui.RespondError(req, err)
+		}
+
+		// Requests that talk to a tournament site run in their own goroutine,
+		// so that the GUI can keep making other requests, e.g. to update
+		// scores, while waiting for them. They're answered asynchronously, and
+		// can be cancelled by their ID until then.
+		inflight := make(map[string]context.CancelFunc)
+		// Goroutines hand their results back to the request loop through here,
+		// so that only the loop ever touches the variables above.
+		finished := make(chan func())
+		loopDone := make(chan struct{})
+		defer close(loopDone)
+
+		respondAsync := func(req ipc.Request, result any, err error) {
+			resp := ipc.Response{ID: req.ID, Result: result}
 			if err != nil {
-				respond("err", fmt.Sprintf("Error: %s", err))
-				break
+				resp.Error = err.Error()
 			}
-			respond("ok",
-			 "Successfully fetched stream match.",
-			 playerOne.Name,
-			 playerOne.Country,
-			 "0",
-			 playerOne.Team,
-			 playerTwo.Name,
-			 playerTwo.Country,
-			 "0",
-			 playerTwo.Team)
-		case "fetchbracket":
-			startggInputs.Token = req.Args[0]
-			startggInputs.PhaseGroupId = req.Args[1]
-			bracket, err := startgg.FetchBracket(startggInputs)
-			fmt.Fprintln(stdin, "getbracket__resp")
-			if err != nil {
-				respond("err", fmt.Sprintf("Error: %s", err))
-				break
+			gui.RespondAsync(resp)
+		}
+		// async runs fetch in a goroutine, then apply in the request loop if
+		// fetch succeeded, and responds to req with apply's result. Anything
+		// fetch needs from the request loop must be read before calling async.
+		async := func(
+			req ipc.Request,
+			fetch func(ctx context.Context) error,
+			apply func() (any, error),
+		) {
+			reqCtx, cancel := context.WithCancel(ctx)
+			inflight[req.ID] = cancel
+			go func() {
+				err := catchPanic(func() error { return fetch(reqCtx) })
+				finish := func() {
+					delete(inflight, req.ID)
+					cancelled := reqCtx.Err() != nil
+					cancel()
+					switch {
+					case err != nil && cancelled:
+						respondAsync(req, nil, errors.New("Cancelled."))
+					case err != nil:
+						respondAsync(req, nil, fmt.Errorf("Error: %w", err))
+					default:
+						var result any
+						err := catchPanic(func() (err error) {
+							result, err = apply()
+							return err
+						})
+						respondAsync(req, result, err)
+					}
+				}
+				select {
+				case finished <- finish:
+				case <-loopDone:
+					cancel()
+				}
+			}()
+		}
+
+		requests := ipc.IncomingRequests(r)
+	requestLoop:
+		for {
+			var req ipc.Request
+			select {
+			case finish := <-finished:
+				finish()
+				continue
+			case r, ok := <-requests:
+				if !ok {
+					break requestLoop
+				}
+				req = r
 			}
-			err = WriteBracket(bracket)
-			if err != nil {
-				respond("err", fmt.Sprintf("Error: %s", err))
-				break
+
+			if req.Err != nil {
+				fmt.Printf("IPC error: %s\n", req.Err)
+				respondError(req, req.Err)
+				continue
 			}
-			respond("ok",
-				"Successfully fetched bracket.")
 
+			// A bug in one handler shouldn't take everything down with it. The
+			// request most likely failed before answering, so answer it now.
+			err := catchPanic(func() error {
+				switch req.Method {
+
+				case "forcefocus":
+					err := forceFocus(req.Args[0])
+					if err != nil {
+						fmt.Printf("forcefocus: %s\n", err)
+					}
+					respond("ok")
+
+				case "getstartgg":
+					respond(
+						startggInputs.Token,
+						startggInputs.Slug,
+						startggInputs.PhaseGroupId,
+						startggInputs.Stream,
+						strings.Join(startggInputs.EventIds, " "),
+					)
+
+				case "getwebport":
+					respond(WebPort)
+
+				case "getloaderrors":
+					respond(loadErrs...)
+
+				case "getcountrycodes":
+					respond(startgg.CountryCodes...)
+
+				case "getscoreboard":
+					scoreboard := state.Scoreboard()
+					if req.Version > 0 {
+						respondResult(scoreboard.guiParams(schema))
+						break
+					}
+					respond(scoreboard.guiValues(schema)...)
+
+				case "applyscoreboard":
+					scoreboard, err := state.Update(func(scoreboard *Scoreboard) error {
+						if req.Version > 0 {
+							scoreboard.setGUIParams(schema, req.Params)
+						} else {
+							scoreboard.setGUIValues(schema, req.Args)
+						}
+						// Let syncEntrants pick up the loaded match's teams
+						// if their names were applied.
+						scoreboard.P1entrant = loaded.Entrants[0]
+						scoreboard.P2entrant = loaded.Entrants[1]
+						return nil
+					})
+					switch {
+					case err != nil:
+						respondError(req, err)
+					case req.Version > 0:
+						respondResult(scoreboard.guiParams(schema))
+					default:
+						respond("ok")
+					}
+
+				case "getfields":
+					// Custom fields, as name type label triplets
+					values := make([]string, 0, len(schema)*3)
+					for _, f := range schema {
+						values = append(values, f.Name, string(f.Type), f.Label)
+					}
+					respond(values...)
+
+				case "getpresets":
+					// Names and space-separated field groups, interleaved
+					values := make([]string, 0)
+					for _, p := range presets {
+						values = append(values, p.Name, p.GroupNames())
+					}
+					respond(values...)
+
+				case "savepreset":
+					// Preset is saved from what's in the GUI, applied or not.
+					name := strings.TrimSpace(req.Args[0])
+					if name == "" {
+						respondError(req, errors.New(
+							"Please enter a preset name first.",
+						))
+						break
+					}
+					groups, err := ParseFieldGroups(strings.Fields(req.Args[1]))
+					if err == nil && len(groups) == 0 {
+						err = fmt.Errorf("Please pick at least one field group.")
+					}
+					if err != nil {
+						respondError(req, err)
+						break
+					}
+					preset := Preset{Name: name, Groups: groups}
+					preset.Scoreboard.setGUIValues(schema, req.Args[2:])
+					saved := presets.Save(preset)
+					err = saved.Write(PresetsFile)
+					if err != nil {
+						respondError(req, fmt.Errorf("Error: %w", err))
+						break
+					}
+					presets = saved
+					respond("ok", fmt.Sprintf("Saved preset %s.", name))
+
+				case "loadpreset":
+					// Preset fields are put on top of what's in the GUI, and left
+					// for the user to apply.
+					preset, ok := presets.Find(req.Args[0])
+					if !ok {
+						respondError(req, fmt.Errorf(
+							"Preset %s not found.", req.Args[0],
+						))
+						break
+					}
+					var scoreboard Scoreboard
+					scoreboard.setGUIValues(schema, req.Args[1:])
+					preset.ApplyTo(&scoreboard)
+					respond(append(
+						[]string{"ok", fmt.Sprintf("Loaded preset %s.", preset.Name)},
+						scoreboard.guiValues(schema)...,
+					)...)
+
+				case "deletepreset":
+					remaining := presets.Delete(req.Args[0])
+					err := remaining.Write(PresetsFile)
+					if err != nil {
+						respondError(req, fmt.Errorf("Error: %w", err))
+						break
+					}
+					presets = remaining
+					respond("ok", fmt.Sprintf("Deleted preset %s.", req.Args[0]))
+
+				case "undo":
+					_, err := state.Undo()
+					if err != nil {
+						respondError(req, err)
+						break
+					}
+					respond("ok", "Undone.")
+
+				case "redo":
+					_, err := state.Redo()
+					if err != nil {
+						respondError(req, err)
+						break
+					}
+					respond("ok", "Redone.")
+
+				case "searchplayers":
+					respond(catalog.SearchPlayers(req.Args[0])...)
+
+				case "loadcharacters":
+					respond(catalog.Characters()...)
+
+				case "loadstages":
+					respond(catalog.Stages()...)
+
+				// Async requests, which all respond with a message to show.
+
+				case "cancel":
+					if cancel, ok := inflight[req.Params["id"]]; ok {
+						cancel()
+					}
+					respondResult(nil)
+
+				case "fetchplayers":
+					name, provider := useProvider(req.Args)
+					var ps []players.Player
+					async(req, func(ctx context.Context) (err error) {
+						ps, err = provider.FetchPlayers(ctx, func(fetched, total int) {
+							gui.Command(fmt.Sprintf(
+								"fetchplayers__progress %s %d %d", name, fetched, total,
+							))
+						})
+						return err
+					}, func() (any, error) {
+						catalog.SetPlayers(ps)
+						err := players.Write(PlayersFile, ps)
+						if err != nil {
+							return nil, fmt.Errorf(
+								"Fetched %d players, but couldn't save them: %w",
+								len(ps), err,
+							)
+						}
+						return guiMessage("%s", partly(
+							fmt.Sprintf("Successfully fetched %d players.", len(ps)),
+							saveProvider(name),
+						)), nil
+					})
+
+				case "fetchevents":
+					startggInputs.Token = req.Args[0]
+					startggInputs.Slug = req.Args[1]
+					client.SetToken(startggInputs.Token)
+					slug := startggInputs.Slug
+					var events []startgg.Event
+					async(req, func(ctx context.Context) (err error) {
+						events, err = client.FetchEvents(ctx, slug)
+						return err
+					}, func() (any, error) {
+						saved := saveStartgg()
+						type event struct {
+							Id   string `json:"id"`
+							Name string `json:"name"`
+						}
+						result := make([]event, 0, len(events))
+						for _, e := range events {
+							result = append(result, event{
+								e.Id,
+								fmt.Sprintf("%s (%d entrants)", e.Name, e.NumEntrants),
+							})
+						}
+						return map[string]any{
+							"msg":    partly(fmt.Sprintf("Found %d events.", len(events)), saved),
+							"events": result,
+						}, nil
+					})
+
+				case "fetchnextmatch":
+					name, provider := useProvider(req.Args)
+					stream := ""
+					if name == "startgg" {
+						stream = startggInputs.Stream
+					}
+					var ms []tournament.Match
+					async(req, func(ctx context.Context) (err error) {
+						ms, err = provider.FetchMatches(ctx)
+						return err
+					}, func() (any, error) {
+						matches[name] = ms
+						match, skipped, err := tournament.NextMatch(ms, stream)
+						if err != nil {
+							return nil, err
+						}
+						msg := "Successfully fetched next match."
+						if skipped > 0 {
+							msg = fmt.Sprintf(
+								"Fetched next match, skipped %d match(es) with TBD players.",
+								skipped,
+							)
+						}
+						loaded, loadedProvider = match, name
+						return map[string]any{
+							"msg":   msg,
+							"match": guiMatch(match, nameFormat, roundNames),
+						}, nil
+					})
+
+				case "fetchmatches":
+					name, provider := useProvider(req.Args)
+					var ms []tournament.Match
+					async(req, func(ctx context.Context) (err error) {
+						ms, err = provider.FetchMatches(ctx)
+						return err
+					}, func() (any, error) {
+						matches[name] = ms
+						saved := saveProvider(name)
+						result := make([]map[string]string, 0, len(ms))
+						for _, m := range ms {
+							result = append(result, guiMatch(m, nameFormat, roundNames))
+						}
+						return map[string]any{
+							"msg":     partly(fmt.Sprintf("Found %d matches.", len(ms)), saved),
+							"matches": result,
+						}, nil
+					})
+
+				case "loadmatch":
+					name, id := req.Args[0], req.Args[1]
+					found := false
+					for _, m := range matches[name] {
+						if m.Id == id {
+							loaded, loadedProvider = m, name
+							found = true
+						}
+					}
+					if !found {
+						// Otherwise the previous match would be reported.
+						respondError(req, fmt.Errorf("match %s not found", id))
+						break
+					}
+					respond()
+
+				case "previewreport":
+					result, err := resultFromScoreboard(state.Scoreboard(), loaded)
+					if err != nil {
+						respondError(req, err)
+						break
+					}
+					// Names come from tournament sites and the summary spans
+					// several lines, so it needs the JSON protocol.
+					respondResult(result.Summary())
+
+				case "reportmatch":
+					name, provider := useProvider(req.Args)
+					match := loaded
+					result, err := resultFromScoreboard(state.Scoreboard(), match)
+					if err != nil {
+						respondError(req, err)
+						break
+					}
+					if name != loadedProvider {
+						respondError(req, errors.New(
+							"Loaded match is from another tournament site. "+
+								"Please load it again.",
+						))
+						break
+					}
+					// Not a problem talking to the site, so it's shown as is.
+					var changed error
+					var b bracket.Bracket
+					// The result is in, but the overlay's bracket may not be.
+					var bracketErr error
+					async(req, func(ctx context.Context) error {
+						current, err := provider.FetchMatch(ctx, match.Id)
+						if err != nil {
+							return err
+						}
+						changed = tournament.CheckUnchanged(match, current)
+						if changed != nil {
+							return nil
+						}
+						err = provider.Report(ctx, result)
+						if err != nil {
+							return err
+						}
+						if name == "offline" {
+							// Nobody else will update the overlay's bracket for us.
+							b, bracketErr = provider.FetchBracket(ctx)
+						}
+						return nil
+					}, func() (any, error) {
+						if changed != nil {
+							return nil, changed
+						}
+						if bracketErr == nil && len(b.Sets) > 0 {
+							bracketErr = bracket.Write(BracketFile, b)
+						}
+						if bracketErr != nil {
+							bracketErr = fmt.Errorf(
+								"%s not updated: %w", BracketFile, bracketErr,
+							)
+						}
+						return guiMessage("%s", partly(fmt.Sprintf(
+							"Reported %s: %s %d - %d %s.",
+							match.RoundText,
+							result.Names[0], result.Scores[0],
+							result.Scores[1], result.Names[1],
+						), bracketErr)), nil
+					})
+
+				case "setstream":
+					startggInputs.Stream = req.Args[0]
+					if err := saveStartgg(); err != nil {
+						respondError(req, err)
+						break
+					}
+					respond()
+
+				case "fetchbracket":
+					name, provider := useProvider(req.Args)
+					var b bracket.Bracket
+					async(req, func(ctx context.Context) (err error) {
+						b, err = provider.FetchBracket(ctx)
+						return err
+					}, func() (any, error) {
+						err := bracket.Write(BracketFile, b)
+						if err != nil {
+							return nil, fmt.Errorf("Error: %w", err)
+						}
+						return guiMessage("%s", partly(
+							fmt.Sprintf("Successfully fetched bracket: %d sets.", len(b.Sets)),
+							saveProvider(name),
+						)), nil
+					})
+
+				case "checktoken":
+					startggInputs.Token = req.Args[0]
+					client.SetToken(startggInputs.Token)
+					var gamerTag string
+					async(req, func(ctx context.Context) (err error) {
+						gamerTag, err = client.CheckToken(ctx)
+						return err
+					}, func() (any, error) {
+						if gamerTag == "" {
+							return guiMessage("Token works."), nil
+						}
+						return guiMessage("Token works, logged in as %s.", gamerTag), nil
+					})
 
-		case "clearstartgg":
-			startggInputs = startgg.Inputs{}
-			startggInputs.Write(StartggFile)
+				case "fetchphasegroups":
+					startggInputs.Token = req.Args[0]
+					client.SetToken(startggInputs.Token)
+					eventIds := req.Args[1:]
+					type phaseGroup struct {
+						EventId string `json:"eventid"`
+						Id      string `json:"id"`
+						Name    string `json:"name"`
+					}
+					groups := make([]phaseGroup, 0)
+					async(req, func(ctx context.Context) error {
+						for _, eventId := range eventIds {
+							phases, err := client.FetchPhases(ctx, eventId)
+							if err != nil {
+								return err
+							}
+							for _, phase := range phases {
+								for _, group := range phase.PhaseGroups {
+									groups = append(groups, phaseGroup{
+										eventId, group.Id, describePhaseGroup(phase, group),
+									})
+								}
+							}
+						}
+						return nil
+					}, func() (any, error) {
+						return map[string]any{
+							"msg":    fmt.Sprintf("Found %d phase groups.", len(groups)),
+							"groups": groups,
+						}, nil
+					})
 
-		case "getplayercountry":
-			playerName := req.Args[0]
-			var country string
-			for _, p := range allplayers {
-				if p.Name == playerName {
-					country = p.Country
-					break
+				case "clearstartgg":
+					// Round names aren't editable from the GUI, so keep them.
+					startggInputs = startgg.Inputs{RoundNames: startggInputs.RoundNames}
+					if err := saveStartgg(); err != nil {
+						respondError(req, err)
+						break
+					}
+					respond()
+
+				case "getchallonge":
+					respond(challongeInputs.APIKey, challongeInputs.Tournament)
+
+				case "clearchallonge":
+					challongeInputs = challonge.Inputs{}
+					if err := saveChallonge(); err != nil {
+						respondError(req, err)
+						break
+					}
+					respond()
+
+				case "exportsets":
+					n, csvPath, jsonPath, err := state.History().Export(time.Now())
+					if err != nil {
+						respondError(req, fmt.Errorf("Error: %w", err))
+						break
+					}
+					respond("ok", fmt.Sprintf(
+						"Exported %d sets to %s and %s", n, csvPath, jsonPath,
+					))
+
+				case "getoffline":
+					t, err := offline.Load(OfflineFile)
+					if errors.Is(err, fs.ErrNotExist) {
+						// No offline bracket has been created yet.
+						respond("ok", "", "")
+						break
+					}
+					if err != nil {
+						respondError(req, err)
+						break
+					}
+					respond("ok", t.Name, string(t.Type))
+
+				case "createoffline":
+					t, err := offline.New(
+						req.Args[0], bracket.Type(req.Args[1]), catalog.FindPlayers(""),
+					)
+					if err != nil {
+						respondError(req, err)
+						break
+					}
+					err = t.Write(OfflineFile)
+					if err == nil {
+						err = bracket.Write(BracketFile, t.Bracket())
+					}
+					if err != nil {
+						respondError(req, fmt.Errorf("Error: %w", err))
+						break
+					}
+					respond("ok", fmt.Sprintf(
+						"Created bracket of %d players from %s.", len(t.Entrants), PlayersFile,
+					))
+
+				case "getplayercountry":
+					p, _ := catalog.FindPlayer(req.Args[0])
+					respond(p.Country)
+
+				default:
+					respondError(req, fmt.Errorf("unknown method: %q", req.Method))
 				}
+				return nil
+			})
+			if err != nil {
+				respondError(req, err)
 			}
-			respond(country)
 		}
-	}
 
-	println("Tcl process terminated.")
+		for _, cancel := range inflight {
+			cancel()
+		}
+	}
 }
 
 type Scoreboard struct {
 	Description string `json:"description"`
 	Subtitle    string `json:"subtitle"`
-	Stage    string `json:"stage"`
+	Stage       string `json:"stage"`
 	P1name      string `json:"p1name"`
 	P1country   string `json:"p1country"`
 	P1score     int    `json:"p1score"`
 	P1team      string `json:"p1team"`
-	P1character      string `json:"p1character"`
-	P2name      string `json:"p2name"`
-	P2country   string `json:"p2country"`
-	P2score     int    `json:"p2score"`
-	P2team      string `json:"p2team"`
-	P2character      string `json:"p2character"`
-	C1Title      string `json:"c1title"`
-	C1Subtitle      string `json:"c1subtitle"`
-	C2Title      string `json:"c2title"`
-	C2Subtitle      string `json:"c2subtitle"`
+	P1character string `json:"p1character"`
+	// Full roster of each side, see syncEntrants.
+	P1entrant   players.Entrant `json:"p1entrant"`
+	P2name      string          `json:"p2name"`
+	P2country   string          `json:"p2country"`
+	P2score     int             `json:"p2score"`
+	P2team      string          `json:"p2team"`
+	P2character string          `json:"p2character"`
+	P2entrant   players.Entrant `json:"p2entrant"`
+	C1Title     string          `json:"c1title"`
+	C1Subtitle  string          `json:"c1subtitle"`
+	C2Title     string          `json:"c2title"`
+	C2Subtitle  string          `json:"c2subtitle"`
+	// Fields defined in FieldsFile, by name. They're written to JSON as
+	// if they were built-in, see MarshalJSON.
+	Custom map[string]string `json:"-"`
 }
 
-func initScoreboard() Scoreboard {
+// initScoreboard returns an empty scoreboard if ScoreboardFile does not
+// exist, or can't be read.
+func initScoreboard() (Scoreboard, error) {
 	var scoreboard Scoreboard
 	file, err := os.Open(ScoreboardFile)
-	if err == nil {
-		defer file.Close()
-		bytes, err := ioutil.ReadAll(file)
-		if err != nil {
-			panic(err)
-		}
-		json.Unmarshal(bytes, &scoreboard)
+	if errors.Is(err, fs.ErrNotExist) {
+		return scoreboard, nil
+	}
+	if err != nil {
+		return scoreboard, fmt.Errorf("load scoreboard: %w", err)
 	}
-	return scoreboard
+	defer file.Close()
+	bytes, err := ioutil.ReadAll(file)
+	if err != nil {
+		return scoreboard, fmt.Errorf("load scoreboard: %w", err)
+	}
+	err = json.Unmarshal(bytes, &scoreboard)
+	if err != nil {
+		return Scoreboard{}, fmt.Errorf(
+			"load scoreboard from %s: %w", ScoreboardFile, err,
+		)
+	}
+	return scoreboard, nil
 }
 
-func (s *Scoreboard) Write() {
+func (s *Scoreboard) Write() error {
 	blob, err := json.MarshalIndent(s, "", "    ")
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("write scoreboard: %w", err)
 	}
 	err = ioutil.WriteFile(ScoreboardFile, blob, 0644)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("write scoreboard: %w", err)
+	}
+	return nil
+}
+
+// guiValues returns the fields that the GUI edits, in the order of
+// scoreboard_keys in tcl, followed by custom fields in schema order.
+// TODO: there must be a more... civilized way.
+func (s *Scoreboard) guiValues(schema Schema) []string {
+	values := []string{
+		s.Description,
+		s.Subtitle,
+		s.Stage,
+		s.P1name,
+		s.P1country,
+		strconv.Itoa(s.P1score),
+		s.P1team,
+		s.P1character,
+		s.P2name,
+		s.P2country,
+		strconv.Itoa(s.P2score),
+		s.P2team,
+		s.P2character,
+		s.C1Title,
+		s.C1Subtitle,
+		s.C2Title,
+		s.C2Subtitle,
 	}
+	for _, f := range schema {
+		value, ok := s.Custom[f.Name]
+		if !ok {
+			value = f.Default
+		}
+		values = append(values, value)
+	}
+	return values
 }
 
-type BracketJson struct {
-	Ltop82p1 string `json:"ltop82p1"`
-	Ltop82p1s string `json:"ltop82p1s"`
-	Ltop82p2 string `json:"ltop82p2"`
-	Ltop82p2s string `json:"ltop82p2s"`
-	Ltop81p1 string `json:"ltop81p1"`
-	Ltop81p1s string `json:"ltop81p1s"`
-	Ltop81p2 string `json:"ltop81p2"`
-	Ltop81p2s string `json:"ltop81p2s"`
-	Lq2p1 string `json:"lq2p1"`
-	Lq2p1s string `json:"lq2p1s"`
-	Lq2p2 string `json:"lq2p2"`
-	Lq2p2s string `json:"lq2p2s"`
-	Lq1p1 string `json:"lq1p1"`
-	Lq1p1s string `json:"lq1p1s"`
-	Lq1p2 string `json:"lq1p2"`
-	Lq1p2s string `json:"lq1p2s"`
-	Lsp1 string `json:"lsp1"`
-	Lsp1s string `json:"lsp1s"`
-	Lsp2 string `json:"lsp2"`
-	Lsp2s string `json:"lsp2s"`
-	Lfp1 string `json:"lfp1"`
-	Lfp1s string `json:"lfp1s"`
-	Lfp2 string `json:"lfp2"`
-	Lfp2s string `json:"lfp2s"`
-	Ws2p1 string `json:"ws2p1"`
-	Ws2p1s string `json:"ws2p1s"`
-	Ws2p2 string `json:"ws2p2"`
-	Ws2p2s string `json:"ws2p2s"`
-	Ws1p1 string `json:"ws1p1"`
-	Ws1p1s string `json:"ws1p1s"`
-	Ws1p2 string `json:"ws1p2"`
-	Ws1p2s string `json:"ws1p2s"`
-	Wfp1 string `json:"wfp1"`
-	Wfp1s string `json:"wfp1s"`
-	Wfp2 string `json:"wfp2"`
-	Wfp2s string `json:"wfp2s"`
-	Gfp1 string `json:"gfp1"`
-	Gfp1s string `json:"gfp1s"`
-	Gfp2 string `json:"gfp2"`
-	Gfp2s string `json:"gfp2s"`
+// setGUIValues is the reverse of guiValues. Custom fields are left for the
+// schema to check when applied.
+func (s *Scoreboard) setGUIValues(schema Schema, values []string) {
+	s.Description = values[0]
+	s.Subtitle = values[1]
+	s.Stage = values[2]
+	s.P1name = values[3]
+	s.P1country = values[4]
+	s.P1score, _ = strconv.Atoi(values[5])
+	s.P1team = values[6]
+	s.P1character = values[7]
+	s.P2name = values[8]
+	s.P2country = values[9]
+	s.P2score, _ = strconv.Atoi(values[10])
+	s.P2team = values[11]
+	s.P2character = values[12]
+	s.C1Title = values[13]
+	s.C1Subtitle = values[14]
+	s.C2Title = values[15]
+	s.C2Subtitle = values[16]
+	custom := make(map[string]string, len(schema))
+	for i, f := range schema {
+		if 17+i < len(values) {
+			custom[f.Name] = values[17+i]
+		}
+	}
+	s.Custom = custom
 }
 
-//TODO: Allow arbitrary sizes for phase group
-func WriteBracket(bracket startgg.Bracket) error {
-	//matches := []string{ "ltop82", "ltop81", "lq2", "lq1", "ls", "lf", "ws2", "ws1", "wf", "gf"}
-	if len(bracket) < 10 {
-		return fmt.Errorf("Too few matches retrieved from phase. %d matches", len(bracket)) 
-	}
-	bracketJson := BracketJson{
-		Lfp1 : bracket[0].PlayerOne.Name,
-		Lfp1s : bracket[0].PlayerOne.Score,
-		Lfp2 : bracket[0].PlayerTwo.Name,
-		Lfp2s : bracket[0].PlayerTwo.Score,
-		Lsp1 : bracket[1].PlayerOne.Name,
-		Lsp1s : bracket[1].PlayerOne.Score,
-		Lsp2 : bracket[1].PlayerTwo.Name,
-		Lsp2s : bracket[1].PlayerTwo.Score,
-		Lq1p1 : bracket[2].PlayerOne.Name,
-		Lq1p1s : bracket[2].PlayerOne.Score,
-		Lq1p2 : bracket[2].PlayerTwo.Name,
-		Lq1p2s : bracket[2].PlayerTwo.Score,
-		Lq2p1 : bracket[3].PlayerOne.Name,
-		Lq2p1s : bracket[3].PlayerOne.Score,
-		Lq2p2 : bracket[3].PlayerTwo.Name,
-		Lq2p2s : bracket[3].PlayerTwo.Score,
-		Ltop81p1 : bracket[4].PlayerOne.Name,
-		Ltop81p1s : bracket[4].PlayerOne.Score,
-		Ltop81p2 : bracket[4].PlayerTwo.Name,
-		Ltop81p2s : bracket[4].PlayerTwo.Score,
-		Ltop82p1 : bracket[5].PlayerOne.Name,
-		Ltop82p1s : bracket[5].PlayerOne.Score,
-		Ltop82p2 : bracket[5].PlayerTwo.Name,
-		Ltop82p2s : bracket[5].PlayerTwo.Score,
-		Ws2p1 : bracket[6].PlayerOne.Name,
-		Ws2p1s : bracket[6].PlayerOne.Score,
-		Ws2p2 : bracket[6].PlayerTwo.Name,
-		Ws2p2s : bracket[6].PlayerTwo.Score,
-		Ws1p1 : bracket[7].PlayerOne.Name,
-		Ws1p1s : bracket[7].PlayerOne.Score,
-		Ws1p2 : bracket[7].PlayerTwo.Name,
-		Ws1p2s : bracket[7].PlayerTwo.Score,
-		Wfp1 : bracket[8].PlayerOne.Name,
-		Wfp1s : bracket[8].PlayerOne.Score,
-		Wfp2 : bracket[8].PlayerTwo.Name,
-		Wfp2s : bracket[8].PlayerTwo.Score,
-		Gfp1 : bracket[9].PlayerOne.Name,
-		Gfp1s : bracket[9].PlayerOne.Score,
-		Gfp2 : bracket[9].PlayerTwo.Name,
-		Gfp2s : bracket[9].PlayerTwo.Score,
-	}
-
-	blob, err := json.MarshalIndent(bracketJson, "", "    ")
-	if err != nil {
-		panic(err)
+// guiFields names the built-in values of guiValues, in the same order.
+var guiFields = []string{
+	"description", "subtitle", "stage",
+	"p1name", "p1country", "p1score", "p1team", "p1character",
+	"p2name", "p2country", "p2score", "p2team", "p2character",
+	"c1title", "c1subtitle", "c2title", "c2subtitle",
+}
+
+func guiFieldNames(schema Schema) []string {
+	names := append([]string{}, guiFields...)
+	for _, f := range schema {
+		names = append(names, f.Name)
 	}
-	err = os.WriteFile(BracketFile, blob, 0644)
-	if err != nil {
-		panic(err)
+	return names
+}
+
+// guiParams is guiValues by name, for the JSON protocol.
+func (s *Scoreboard) guiParams(schema Schema) map[string]string {
+	values := s.guiValues(schema)
+	params := make(map[string]string, len(values))
+	for i, name := range guiFieldNames(schema) {
+		params[name] = values[i]
+	}
+	return params
+}
+
+// setGUIParams is the reverse of guiParams. Fields that aren't in params are
+// left alone, and unknown ones are kept for the schema to reject.
+func (s *Scoreboard) setGUIParams(schema Schema, params map[string]string) {
+	names := guiFieldNames(schema)
+	values := s.guiValues(schema)
+	for i, name := range names {
+		if value, ok := params[name]; ok {
+			values[i] = value
+		}
+	}
+	s.setGUIValues(schema, values)
+	for name, value := range params {
+		if _, ok := s.Custom[name]; !ok && !contains(guiFields, name) {
+			s.Custom[name] = value
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IncrementScore adds amount (which may be negative) to player "1" or "2"'s
+// score. Scores never go below zero.
+func (s *Scoreboard) IncrementScore(player string, amount int) error {
+	var score *int
+	switch player {
+	case "1":
+		score = &s.P1score
+	case "2":
+		score = &s.P2score
+	default:
+		return fmt.Errorf("invalid player: %q, must be 1 or 2", player)
+	}
+	*score += amount
+	if *score < 0 {
+		*score = 0
 	}
 	return nil
 }
 
-func FromCSVFile(filepath string) []string {
+func (s *Scoreboard) ResetScores() {
+	s.P1score = 0
+	s.P2score = 0
+}
+
+func (s *Scoreboard) SwapPlayers() {
+	s.P1name, s.P2name = s.P2name, s.P1name
+	s.P1country, s.P2country = s.P2country, s.P1country
+	s.P1score, s.P2score = s.P2score, s.P1score
+	s.P1team, s.P2team = s.P2team, s.P1team
+	s.P1character, s.P2character = s.P2character, s.P1character
+	s.P1entrant, s.P2entrant = s.P2entrant, s.P1entrant
+}
+
+// guiMatch is how matches are sent to the GUI, see fetchmatches in tcl.
+func guiMatch(
+	m tournament.Match, f players.NameFormat, roundNames tournament.RoundNames,
+) map[string]string {
+	p1, p2 := m.Entrants[0], m.Entrants[1]
+	return map[string]string{
+		"stream":    m.Stream,
+		"id":        m.Id,
+		"roundtext": m.RoundText,
+		"state":     string(m.State),
+		"p1name":    f.Name(p1),
+		"p1country": p1.Country(),
+		"p1team":    p1.Sponsor(),
+		"p2name":    f.Name(p2),
+		"p2country": p2.Country(),
+		"p2team":    p2.Sponsor(),
+		"subtitle":  roundNames.Subtitle(m),
+	}
+}
+
+// loadErrors are what couldn't be loaded at startup, for the GUI to show.
+type loadErrors []string
+
+// ignore notes err, if any, then carries on without what couldn't be loaded.
+func (l *loadErrors) ignore(what string, err error) {
+	if err == nil {
+		return
+	}
+	// E.g. errors.Join's, which wouldn't fit on an IPC line otherwise.
+	msg := strings.ReplaceAll(fmt.Sprintf("Ignoring %s: %s", what, err), "\n", "; ")
+	fmt.Println(msg)
+	*l = append(*l, msg)
+}
+
+// catchPanic returns fn's error, or the panic it recovered from as one.
+func catchPanic(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("Panic: %v\n%s", r, debug.Stack())
+			err = fmt.Errorf("Internal error: %v", r)
+		}
+	}()
+	return fn()
+}
+
+// guiMessage is the result of async requests that only have a message for
+// the GUI to show.
+func guiMessage(format string, a ...any) map[string]string {
+	return map[string]string{"msg": fmt.Sprintf(format, a...)}
+}
+
+// partly adds to the message of a request that succeeded what went wrong
+// after, if anything, e.g. "Found 3 events, but couldn't save settings: ..."
+func partly(msg string, err error) string {
+	if err == nil {
+		return msg
+	}
+	return fmt.Sprintf("%s, but %s", strings.TrimSuffix(msg, "."), err)
+}
+
+// describePhaseGroup returns e.g. "Top 8 (double elimination, in progress)"
+func describePhaseGroup(phase startgg.Phase, group startgg.PhaseGroup) string {
+	details := make([]string, 0, 2)
+	if group.BracketType != "" {
+		details = append(
+			details, strings.ReplaceAll(string(group.BracketType), "_", " "),
+		)
+	}
+	switch group.State {
+	case bracket.InProgress:
+		details = append(details, "in progress")
+	case bracket.Completed:
+		details = append(details, "completed")
+	}
+	name := phase.GroupName(group)
+	if len(details) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, strings.Join(details, ", "))
+}
+
+// syncEntrants makes sure each side's entrant matches the name shown on
+// stream. A team is kept as long as its formatted name is still on either
+// side, so editing other fields or swapping sides doesn't lose its members.
+// Any other name becomes a single player made of that side's fields.
+func (s *Scoreboard) syncEntrants(prev Scoreboard, f players.NameFormat) {
+	candidates := []players.Entrant{
+		s.P1entrant, s.P2entrant, prev.P1entrant, prev.P2entrant,
+	}
+	s.P1entrant = entrantNamed(
+		players.Player{Name: s.P1name, Country: s.P1country, Team: s.P1team},
+		candidates, f,
+	)
+	s.P2entrant = entrantNamed(
+		players.Player{Name: s.P2name, Country: s.P2country, Team: s.P2team},
+		candidates, f,
+	)
+}
+
+func entrantNamed(
+	p players.Player, candidates []players.Entrant, f players.NameFormat,
+) players.Entrant {
+	for _, c := range candidates {
+		if c.IsTeam() && f.Name(c) == p.Name {
+			return c
+		}
+	}
+	return players.Single(p)
+}
+
+// FromCSVFile reads a single-column csv file. If file does not exist, it
+// returns an empty slice.
+func FromCSVFile(filepath string) ([]string, error) {
 	result := make([]string, 0)
 
 	f, err := os.Open(filepath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return result, nil
+	}
 	if err != nil {
-		return result
+		return result, fmt.Errorf("load %s: %w", filepath, err)
 	}
 	defer f.Close()
 
 	reader := csv.NewReader(f)
 	reader.FieldsPerRecord = 1
 	records, err := reader.ReadAll()
-	// TODO: should probably return error so GUI can show an error message
-	// instead of crashing.
 	if err != nil {
-		log.Fatalf("csv parse error for %s: %s", filepath, err)
+		return result, fmt.Errorf("load %s: %w", filepath, err)
 	}
 
 	for _, record := range records {
 		result = append(result, record[0])
 	}
 
-	return result
-}
\ No newline at end of file
+	return result, nil
+}
@@ -2,6 +2,19 @@
 
 package main
 
+import "fmt"
+
 const DefaultTclPath = "tclsh"
 
+const TclInstallHint = "Install Tcl/Tk with your package manager, " +
+	"e.g. sudo apt install tk on Debian or Ubuntu, " +
+	"or pass the path to tclsh with -tcl."
+
 func forceFocus(handle string) error { return nil }
+
+// guiUnavailable tells the user why there's no GUI, then keeps serving the
+// overlay until GORTS is stopped.
+func guiUnavailable(msg string) {
+	fmt.Printf("%s\nServing the overlay and HTTP API only.\n", msg)
+	waitForSignal()
+}
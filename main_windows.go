@@ -5,12 +5,17 @@ package main
 import (
 	"fmt"
 	"strconv"
+	"syscall"
 
 	"github.com/lxn/win"
 )
 
 const DefaultTclPath = "./IronTcl/bin/wish86t.exe"
 
+const TclInstallHint = "The GORTS zip comes with IronTcl, " +
+	"which must be extracted next to gorts.exe. " +
+	"Otherwise install Tcl/Tk and pass the path to wish or tclsh with -tcl."
+
 func forceFocus(handle string) error {
 	hex := handle[2:] // trim the "0x" prefix
 	uintHandle, err := strconv.ParseUint(hex, 16, 64)
@@ -23,3 +28,15 @@ func forceFocus(handle string) error {
 	win.SetFocus(h)
 	return nil
 }
+
+// guiUnavailable tells the user why there's no GUI, and keeps serving the
+// overlay until they close the message. Release builds have no console (see
+// -H=windowsgui in the Makefile), so printing would show nothing, and the
+// message box is the only way to quit short of the task manager.
+func guiUnavailable(msg string) {
+	fmt.Println(msg)
+	text, _ := syscall.UTF16PtrFromString(msg + "\n\n" +
+		"The overlay and HTTP API are served until you close this message.")
+	caption, _ := syscall.UTF16PtrFromString("GORTS")
+	win.MessageBox(0, text, caption, win.MB_OK|win.MB_ICONWARNING)
+}
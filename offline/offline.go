@@ -0,0 +1,496 @@
+// Package offline runs a bracket locally, for when there's no internet or
+// no tournament site at all. Everything is kept in a single JSON file so the
+// bracket survives restarts, and can be fixed by hand if need be.
+package offline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"go.imnhan.com/gorts/bracket"
+	"go.imnhan.com/gorts/players"
+)
+
+// Special slot values, besides indexes into Tournament.Entrants.
+const (
+	// Not known yet, waiting on a previous set.
+	TBD = -1
+	// Nobody will ever play here. Sets with a bye are skipped.
+	Bye = -2
+)
+
+type Tournament struct {
+	Name string       `json:"name"`
+	Type bracket.Type `json:"type"`
+	// In seeding order.
+	Entrants []players.Player `json:"entrants"`
+	// In play order: every set comes after the sets it's fed from.
+	Sets []Set `json:"sets"`
+}
+
+type Set struct {
+	Id         string       `json:"id"`
+	Identifier string       `json:"identifier"`
+	Round      int          `json:"round"`
+	Side       bracket.Side `json:"side"`
+	Reset      bool         `json:"reset"`
+	// Where each slot's entrant comes from. Empty SetId means seeded.
+	Sources [2]Source `json:"sources"`
+	// Index into Tournament.Entrants of each slot, or TBD or Bye.
+	// Seeded slots are set on creation, others are filled from sources.
+	Entrants [2]int `json:"entrants"`
+	Scores   [2]int `json:"scores"`
+	// Index of the winning slot, or -1 if not played yet.
+	Winner int `json:"winner"`
+}
+
+type Source struct {
+	SetId     string            `json:"setid"`
+	Placement bracket.Placement `json:"placement"`
+}
+
+func (s *Set) Ready() bool {
+	return s.Entrants[0] >= 0 && s.Entrants[1] >= 0
+}
+
+func (s *Set) Completed() bool {
+	return s.Winner != -1
+}
+
+func (s *Set) IsBye() bool {
+	return s.Entrants[0] == Bye || s.Entrants[1] == Bye
+}
+
+// placed returns the set's winner or loser, TBD if it hasn't been played yet.
+func (s *Set) placed(placement bracket.Placement) int {
+	switch {
+	case s.IsBye() && s.Completed():
+		// Whoever got the bye goes through, and nobody drops down.
+		if placement == bracket.Loser {
+			return Bye
+		}
+		return s.Entrants[s.Winner]
+	case !s.Completed():
+		return TBD
+	case placement == bracket.Winner:
+		return s.Entrants[s.Winner]
+	default:
+		return s.Entrants[1-s.Winner]
+	}
+}
+
+// New seeds entrants into a fresh bracket of type t.
+func New(name string, t bracket.Type, entrants []players.Player) (Tournament, error) {
+	result := Tournament{Name: name, Type: t, Entrants: entrants}
+	if len(entrants) < 2 {
+		return result, fmt.Errorf(
+			"Need at least 2 players to make a bracket, got %d.", len(entrants),
+		)
+	}
+
+	var b builder
+	switch t {
+	case bracket.SingleElimination:
+		b.elimination(len(entrants), false)
+	case bracket.DoubleElimination:
+		b.elimination(len(entrants), true)
+	case bracket.RoundRobin:
+		b.roundRobin(len(entrants))
+	default:
+		return result, fmt.Errorf("Unknown bracket type: %q", t)
+	}
+	result.Sets = b.sets
+	result.resolve()
+	return result, nil
+}
+
+func Load(filepath string) (Tournament, error) {
+	var result Tournament
+	blob, err := os.ReadFile(filepath)
+	if err != nil {
+		return result, fmt.Errorf("load offline bracket: %w", err)
+	}
+	err = json.Unmarshal(blob, &result)
+	if err != nil {
+		return result, fmt.Errorf("load offline bracket from %s: %w", filepath, err)
+	}
+	result.resolve()
+	return result, nil
+}
+
+func (t *Tournament) Write(filepath string) error {
+	blob, err := json.MarshalIndent(t, "", "    ")
+	if err != nil {
+		return fmt.Errorf("write offline bracket: %w", err)
+	}
+	err = os.WriteFile(filepath, blob, 0644)
+	if err != nil {
+		return fmt.Errorf("write offline bracket: %w", err)
+	}
+	return nil
+}
+
+func (t *Tournament) Set(id string) (*Set, bool) {
+	for i := range t.Sets {
+		if t.Sets[i].Id == id {
+			return &t.Sets[i], true
+		}
+	}
+	return nil, false
+}
+
+// Report records the result of a set and advances its entrants. Scores and
+// winner are in slot order.
+func (t *Tournament) Report(id string, scores [2]int, winner int) error {
+	set, ok := t.Set(id)
+	if !ok {
+		return fmt.Errorf("Set %s not found in offline bracket.", id)
+	}
+	if set.Completed() {
+		return fmt.Errorf("%s was already reported.", t.RoundText(set))
+	}
+	if !set.Ready() {
+		return fmt.Errorf("%s is still waiting on TBD players.", t.RoundText(set))
+	}
+	if winner != 0 && winner != 1 {
+		return fmt.Errorf("invalid winner: %d, must be 0 or 1", winner)
+	}
+	set.Scores = scores
+	set.Winner = winner
+	t.resolve()
+	return nil
+}
+
+// Next returns the first set in play order that's ready to be played.
+func (t *Tournament) Next() (*Set, bool) {
+	for i := range t.Sets {
+		set := &t.Sets[i]
+		if set.Ready() && !set.Completed() {
+			return set, true
+		}
+	}
+	return nil, false
+}
+
+// resolve fills every slot that has a source from the results so far, and
+// gives byes to whoever is up against nobody.
+func (t *Tournament) resolve() {
+	for i := range t.Sets {
+		set := &t.Sets[i]
+		if set.Reset {
+			t.resolveReset(set)
+			continue
+		}
+		for slot, src := range set.Sources {
+			if src.SetId == "" {
+				continue
+			}
+			prereq, ok := t.Set(src.SetId)
+			if !ok {
+				continue
+			}
+			set.Entrants[slot] = prereq.placed(src.Placement)
+		}
+		switch {
+		case set.Entrants[0] == Bye && set.Entrants[1] == Bye:
+			set.Winner = 0
+		case set.Entrants[0] == Bye && set.Entrants[1] != TBD:
+			set.Winner = 1
+		case set.Entrants[1] == Bye && set.Entrants[0] != TBD:
+			set.Winner = 0
+		}
+	}
+}
+
+// resolveReset only puts grand finalists in the reset if the losers side
+// won the first grand final.
+func (t *Tournament) resolveReset(set *Set) {
+	gf, ok := t.Set(set.Sources[0].SetId)
+	if !ok {
+		return
+	}
+	switch gf.Winner {
+	case -1:
+		set.Entrants = [2]int{TBD, TBD}
+	case 0:
+		set.Entrants = [2]int{Bye, Bye}
+		set.Winner = 0
+	default:
+		set.Entrants = gf.Entrants
+	}
+}
+
+// RoundText names rounds the way start.gg does, so that the same round name
+// mappings work for all providers.
+func (t *Tournament) RoundText(set *Set) string {
+	if t.Type == bracket.RoundRobin {
+		return fmt.Sprintf("Round %d", set.Round)
+	}
+	if set.Side == bracket.GrandFinal {
+		if set.Reset {
+			return "Grand Final Reset"
+		}
+		return "Grand Final"
+	}
+
+	last := 0
+	for _, s := range t.Sets {
+		if s.Side == set.Side && abs(s.Round) > last {
+			last = abs(s.Round)
+		}
+	}
+	fromLast := last - abs(set.Round)
+
+	if t.Type == bracket.SingleElimination {
+		switch fromLast {
+		case 0:
+			return "Final"
+		case 1:
+			return "Semi-Final"
+		case 2:
+			return "Quarter-Final"
+		}
+		return fmt.Sprintf("Round %d", set.Round)
+	}
+
+	side := "Winners "
+	if set.Side == bracket.Losers {
+		side = "Losers "
+	}
+	switch fromLast {
+	case 0:
+		return side + "Final"
+	case 1:
+		return side + "Semi-Final"
+	}
+	return fmt.Sprintf("%sRound %d", side, abs(set.Round))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Bracket describes every set that will actually be played, i.e. without
+// byes, for bracket.json.
+func (t *Tournament) Bracket() bracket.Bracket {
+	result := bracket.Bracket{Name: t.Name, Type: t.Type, Sets: []bracket.Set{}}
+	next, _ := t.Next()
+	for i := range t.Sets {
+		set := &t.Sets[i]
+		if set.IsBye() {
+			continue
+		}
+		s := bracket.Set{
+			ID:         set.Id,
+			Identifier: set.Identifier,
+			Round:      set.Round,
+			Side:       set.Side,
+			RoundText:  t.RoundText(set),
+			State:      bracket.Pending,
+			Reset:      set.Reset,
+			Next:       set == next,
+		}
+		if set.Completed() {
+			s.State = bracket.Completed
+		}
+		for slot := range s.Slots {
+			s.Slots[slot] = bracket.Slot{
+				PrereqSetID:     set.Sources[slot].SetId,
+				PrereqPlacement: set.Sources[slot].Placement,
+			}
+			if e := set.Entrants[slot]; e >= 0 {
+				s.Slots[slot].EntrantID = EntrantId(e)
+				s.Slots[slot].Entrant = t.Entrants[e].Name
+			}
+			if set.Completed() {
+				score := set.Scores[slot]
+				s.Slots[slot].Score = &score
+				s.Slots[slot].Winner = set.Winner == slot
+			}
+		}
+		result.Sets = append(result.Sets, s)
+	}
+	return result
+}
+
+// EntrantId is an entrant's seed, starting from 1.
+func EntrantId(index int) string {
+	return strconv.Itoa(index + 1)
+}
+
+// builder appends sets in play order, numbering them as it goes.
+type builder struct {
+	sets []Set
+}
+
+func (b *builder) add(set Set) string {
+	set.Id = strconv.Itoa(len(b.sets) + 1)
+	set.Identifier = identifier(len(b.sets))
+	set.Winner = -1
+	for slot, src := range set.Sources {
+		if src.SetId != "" {
+			set.Entrants[slot] = TBD
+		}
+	}
+	b.sets = append(b.sets, set)
+	return set.Id
+}
+
+// identifier returns A, B, ..., Z, AA, AB, ... like start.gg does.
+func identifier(n int) string {
+	result := ""
+	for n >= 0 {
+		result = string(rune('A'+n%26)) + result
+		n = n/26 - 1
+	}
+	return result
+}
+
+func winnerOf(id string) Source {
+	return Source{SetId: id, Placement: bracket.Winner}
+}
+
+func loserOf(id string) Source {
+	return Source{SetId: id, Placement: bracket.Loser}
+}
+
+// seedOrder returns the seeds of each first round slot of a bracket of size
+// entrants, so that top seeds only meet in later rounds, e.g. for size 8:
+// 1 vs 8, 4 vs 5, 2 vs 7, 3 vs 6 (zero-based).
+func seedOrder(size int) []int {
+	order := []int{0}
+	for n := 1; n < size; n *= 2 {
+		next := make([]int, 0, n*2)
+		for _, seed := range order {
+			next = append(next, seed, 2*n-1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// elimination builds a single or double elimination bracket, padded with
+// byes to the next power of 2.
+func (b *builder) elimination(numEntrants int, double bool) {
+	size := 2
+	for size < numEntrants {
+		size *= 2
+	}
+
+	seeds := seedOrder(size)
+	winners := make([]string, 0, size/2)
+	for i := 0; i < size; i += 2 {
+		set := Set{Round: 1, Side: bracket.Winners}
+		for slot, seed := range seeds[i : i+2] {
+			set.Entrants[slot] = seed
+			if seed >= numEntrants {
+				set.Entrants[slot] = Bye
+			}
+		}
+		winners = append(winners, b.add(set))
+	}
+
+	// Losers side: every other round takes in the losers of the next
+	// winners round, in reverse order so early rematches are less likely.
+	var losers []string
+	round := 1
+	for len(winners) > 1 {
+		prevWinners := winners
+		winners = b.nextRound(prevWinners, round+1, bracket.Winners)
+		if !double {
+			round++
+			continue
+		}
+		if round == 1 {
+			losers = make([]string, 0, len(prevWinners)/2)
+			for i := 0; i < len(prevWinners); i += 2 {
+				losers = append(losers, b.add(Set{
+					Round:   -1,
+					Side:    bracket.Losers,
+					Sources: [2]Source{loserOf(prevWinners[i]), loserOf(prevWinners[i+1])},
+				}))
+			}
+		} else {
+			losers = b.nextRound(losers, -(2*round - 1), bracket.Losers)
+		}
+		dropping := make([]string, len(losers))
+		for i, id := range winners {
+			if round%2 == 1 {
+				dropping[len(winners)-1-i] = id
+			} else {
+				dropping[i] = id
+			}
+		}
+		for i := range losers {
+			losers[i] = b.add(Set{
+				Round:   -2 * round,
+				Side:    bracket.Losers,
+				Sources: [2]Source{winnerOf(losers[i]), loserOf(dropping[i])},
+			})
+		}
+		round++
+	}
+	if !double {
+		return
+	}
+
+	gf := Set{Round: round + 1, Side: bracket.GrandFinal}
+	gf.Sources[0] = winnerOf(winners[0])
+	if len(losers) == 0 {
+		// Only 2 entrants: there's no losers side to go through.
+		gf.Sources[1] = loserOf(winners[0])
+	} else {
+		gf.Sources[1] = winnerOf(losers[0])
+	}
+	gfId := b.add(gf)
+	b.add(Set{
+		Round:   round + 2,
+		Side:    bracket.GrandFinal,
+		Reset:   true,
+		Sources: [2]Source{winnerOf(gfId), loserOf(gfId)},
+	})
+}
+
+// nextRound pairs up the winners of prev.
+func (b *builder) nextRound(prev []string, round int, side bracket.Side) []string {
+	result := make([]string, 0, len(prev)/2)
+	for i := 0; i+1 < len(prev); i += 2 {
+		result = append(result, b.add(Set{
+			Round:   round,
+			Side:    side,
+			Sources: [2]Source{winnerOf(prev[i]), winnerOf(prev[i+1])},
+		}))
+	}
+	return result
+}
+
+// roundRobin pairs everyone with everyone else using the circle method, so
+// that nobody plays twice in the same round.
+func (b *builder) roundRobin(numEntrants int) {
+	circle := make([]int, 0, numEntrants+1)
+	for i := 0; i < numEntrants; i++ {
+		circle = append(circle, i)
+	}
+	if numEntrants%2 == 1 {
+		circle = append(circle, Bye)
+	}
+	n := len(circle)
+	for round := 1; round < n; round++ {
+		for i := 0; i < n/2; i++ {
+			pair := [2]int{circle[i], circle[n-1-i]}
+			if pair[0] == Bye || pair[1] == Bye {
+				continue
+			}
+			b.add(Set{Round: round, Side: bracket.Winners, Entrants: pair})
+		}
+		// Keep the first entrant in place and rotate everyone else.
+		last := circle[n-1]
+		copy(circle[2:], circle[1:n-1])
+		circle[1] = last
+	}
+}
@@ -0,0 +1,238 @@
+package offline
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.imnhan.com/gorts/bracket"
+	"go.imnhan.com/gorts/players"
+)
+
+func entrants(n int) []players.Player {
+	result := make([]players.Player, n)
+	for i := range result {
+		result[i] = players.Player{Name: fmt.Sprintf("P%d", i+1)}
+	}
+	return result
+}
+
+// playOut reports every set in play order, always won by slot winner, and
+// returns the sets played, i.e. without byes.
+func playOut(t *testing.T, tour *Tournament, winner int) []Set {
+	t.Helper()
+	played := make([]Set, 0)
+	for i := 0; ; i++ {
+		set, ok := tour.Next()
+		if !ok {
+			break
+		}
+		if i > len(tour.Sets) {
+			t.Fatalf("bracket never ends")
+		}
+		if set.IsBye() {
+			t.Fatalf("set %s with a bye is up next: %+v", set.Id, *set)
+		}
+		scores := [2]int{0, 0}
+		scores[winner] = 2
+		if err := tour.Report(set.Id, scores, winner); err != nil {
+			t.Fatalf("report set %s: %s", set.Id, err)
+		}
+		played = append(played, *set)
+	}
+	for _, set := range tour.Sets {
+		if !set.Completed() {
+			t.Fatalf("set %s never completed: %+v", set.Id, set)
+		}
+	}
+	return played
+}
+
+func TestEliminationAnySize(t *testing.T) {
+	for n := 2; n <= 17; n++ {
+		for _, tc := range []struct {
+			typ    bracket.Type
+			winner int
+			// How many sets get played with n entrants.
+			want int
+		}{
+			{bracket.SingleElimination, 0, n - 1},
+			{bracket.SingleElimination, 1, n - 1},
+			// Winners side finalist wins the grand final: no reset.
+			{bracket.DoubleElimination, 0, 2*n - 2},
+			// Losers side finalist always wins: reset is played.
+			{bracket.DoubleElimination, 1, 2*n - 1},
+		} {
+			name := fmt.Sprintf("%s/%d/slot%d", tc.typ, n, tc.winner)
+			t.Run(name, func(t *testing.T) {
+				tour, err := New("Test", tc.typ, entrants(n))
+				if err != nil {
+					t.Fatal(err)
+				}
+				played := playOut(t, &tour, tc.winner)
+				if len(played) != tc.want {
+					t.Errorf("played %d sets, want %d", len(played), tc.want)
+				}
+				seen := make(map[int]bool)
+				for _, set := range played {
+					seen[set.Entrants[0]] = true
+					seen[set.Entrants[1]] = true
+				}
+				if len(seen) != n {
+					t.Errorf("%d entrants played, want %d", len(seen), n)
+				}
+			})
+		}
+	}
+}
+
+func TestByesGoToTopSeeds(t *testing.T) {
+	tour, err := New("Test", bracket.SingleElimination, entrants(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Seed 1 has a bye, so the only playable set is 2 vs 3.
+	next, ok := tour.Next()
+	if !ok || next.Entrants != [2]int{1, 2} {
+		t.Fatalf("next set = %+v, want seeds 2 vs 3", next)
+	}
+	b := tour.Bracket()
+	if len(b.Sets) != 2 {
+		t.Errorf("bracket has %d sets, want 2 without the bye", len(b.Sets))
+	}
+	final := b.Sets[len(b.Sets)-1]
+	if final.Slots[0].Entrant != "P1" {
+		t.Errorf("final slot 0 = %q, want P1 through the bye", final.Slots[0].Entrant)
+	}
+}
+
+func TestGrandFinalReset(t *testing.T) {
+	for _, tc := range []struct {
+		gfWinner  int
+		wantReset bool
+	}{
+		{0, false},
+		{1, true},
+	} {
+		tour, err := New("Test", bracket.DoubleElimination, entrants(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Winners final, losers final doesn't exist with 2 entrants.
+		ws, _ := tour.Next()
+		tour.Report(ws.Id, [2]int{2, 0}, 0)
+		gf, _ := tour.Next()
+		if gf.Side != bracket.GrandFinal || gf.Reset {
+			t.Fatalf("expected grand final, got %+v", *gf)
+		}
+		tour.Report(gf.Id, [2]int{0, 0}, tc.gfWinner)
+		reset, ok := tour.Next()
+		if ok != tc.wantReset {
+			t.Fatalf("gf won by slot %d: reset playable = %t, want %t",
+				tc.gfWinner, ok, tc.wantReset)
+		}
+		if ok && (!reset.Reset || reset.Entrants != gf.Entrants) {
+			t.Errorf("reset = %+v, want grand finalists %v", *reset, gf.Entrants)
+		}
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	for n := 2; n <= 7; n++ {
+		tour, err := New("Test", bracket.RoundRobin, entrants(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := n * (n - 1) / 2; len(tour.Sets) != want {
+			t.Errorf("%d entrants: %d sets, want %d", n, len(tour.Sets), want)
+		}
+		pairs := make(map[[2]int]bool)
+		perRound := make(map[[2]int]bool)
+		for _, set := range tour.Sets {
+			a, b := set.Entrants[0], set.Entrants[1]
+			if a > b {
+				a, b = b, a
+			}
+			if pairs[[2]int{a, b}] {
+				t.Errorf("%d entrants: %d vs %d played twice", n, a, b)
+			}
+			pairs[[2]int{a, b}] = true
+			for _, e := range set.Entrants {
+				if perRound[[2]int{set.Round, e}] {
+					t.Errorf("%d entrants: %d plays twice in round %d", n, e, set.Round)
+				}
+				perRound[[2]int{set.Round, e}] = true
+			}
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	if _, err := New("Test", bracket.SingleElimination, entrants(1)); err == nil {
+		t.Error("1 entrant: expected error")
+	}
+	if _, err := New("Test", bracket.Type("swiss"), entrants(4)); err == nil {
+		t.Error("unknown type: expected error")
+	}
+}
+
+func TestReportErrors(t *testing.T) {
+	tour, err := New("Test", bracket.SingleElimination, entrants(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := tour.Next()
+	final := &tour.Sets[len(tour.Sets)-1]
+
+	if err := tour.Report("nope", [2]int{2, 0}, 0); err == nil {
+		t.Error("unknown set: expected error")
+	}
+	if err := tour.Report(first.Id, [2]int{2, 0}, 2); err == nil {
+		t.Error("invalid winner: expected error")
+	}
+	if err := tour.Report(final.Id, [2]int{2, 0}, 0); err == nil {
+		t.Error("set with TBD players: expected error")
+	}
+	if err := tour.Report(first.Id, [2]int{2, 0}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := tour.Report(first.Id, [2]int{2, 0}, 0); err == nil {
+		t.Error("already reported: expected error")
+	}
+}
+
+func TestSeedOrder(t *testing.T) {
+	got := seedOrder(8)
+	want := []int{0, 7, 3, 4, 1, 6, 2, 5}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("seedOrder(8) = %v, want %v", got, want)
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	for n, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 52: "BA"} {
+		if got := identifier(n); got != want {
+			t.Errorf("identifier(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestRoundText(t *testing.T) {
+	tour, err := New("Test", bracket.DoubleElimination, entrants(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for i := range tour.Sets {
+		names[tour.RoundText(&tour.Sets[i])] = true
+	}
+	for _, want := range []string{
+		"Winners Round 1", "Winners Semi-Final", "Winners Final",
+		"Losers Round 1", "Losers Semi-Final", "Losers Final",
+		"Grand Final", "Grand Final Reset",
+	} {
+		if !names[want] {
+			t.Errorf("no round named %q in %v", want, names)
+		}
+	}
+}
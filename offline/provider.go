@@ -0,0 +1,112 @@
+package offline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"go.imnhan.com/gorts/bracket"
+	"go.imnhan.com/gorts/players"
+	"go.imnhan.com/gorts/tournament"
+)
+
+// Provider makes the bracket saved at Path a tournament.Provider. It's read
+// from disk on every call so hand edits are picked up right away.
+type Provider struct {
+	Path string
+}
+
+func (p *Provider) load() (Tournament, error) {
+	t, err := Load(p.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return t, fmt.Errorf("No offline bracket yet. Create one first.")
+	}
+	return t, err
+}
+
+// FetchPlayers returns the bracket's entrants in seeding order.
+func (p *Provider) FetchPlayers(
+	ctx context.Context, progress func(fetched, total int),
+) ([]players.Player, error) {
+	t, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	if progress != nil {
+		progress(len(t.Entrants), len(t.Entrants))
+	}
+	return t.Entrants, nil
+}
+
+// FetchMatches returns every set that's left to play, in play order.
+func (p *Provider) FetchMatches(ctx context.Context) ([]tournament.Match, error) {
+	t, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]tournament.Match, 0)
+	for i := range t.Sets {
+		set := &t.Sets[i]
+		if set.Completed() || set.IsBye() {
+			continue
+		}
+		matches = append(matches, t.toMatch(set))
+	}
+	return matches, nil
+}
+
+func (p *Provider) FetchMatch(ctx context.Context, id string) (tournament.Match, error) {
+	t, err := p.load()
+	if err != nil {
+		return tournament.Match{}, err
+	}
+	set, ok := t.Set(id)
+	if !ok {
+		return tournament.Match{}, fmt.Errorf("Set %s not found in offline bracket.", id)
+	}
+	return t.toMatch(set), nil
+}
+
+func (p *Provider) FetchBracket(ctx context.Context) (bracket.Bracket, error) {
+	t, err := p.load()
+	if err != nil {
+		return bracket.Bracket{}, err
+	}
+	return t.Bracket(), nil
+}
+
+func (p *Provider) Report(ctx context.Context, result tournament.Result) error {
+	winner := result.Winner()
+	if winner == -1 {
+		return fmt.Errorf("Scores are tied, there's no winner to report.")
+	}
+	t, err := p.load()
+	if err != nil {
+		return err
+	}
+	err = t.Report(result.Match.Id, result.Scores, winner)
+	if err != nil {
+		return err
+	}
+	return t.Write(p.Path)
+}
+
+func (t *Tournament) toMatch(set *Set) tournament.Match {
+	result := tournament.Match{
+		Id:        set.Id,
+		RoundText: t.RoundText(set),
+		State:     bracket.Pending,
+	}
+	if set.Completed() {
+		result.State = bracket.Completed
+	}
+	for slot, e := range set.Entrants {
+		if e < 0 {
+			continue
+		}
+		result.EntrantIds[slot] = EntrantId(e)
+		result.Entrants[slot] = players.Single(t.Entrants[e])
+	}
+	return result
+}
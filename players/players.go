@@ -2,37 +2,136 @@ package players
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
-	"log"
+	"io/fs"
 	"os"
 	"regexp"
 	"strings"
 )
 
 type Player struct {
-	Name    string
-	Country string
-	Team    string
+	Name    string `json:"name"`
+	Country string `json:"country"`
+	Team    string `json:"team"`
+}
+
+// Entrant is one side of a set: a single player, or a team of several
+// players for doubles and 2v2 games.
+type Entrant struct {
+	// Name of a doubles team, if it has one. Unused for single players.
+	Team    string   `json:"team"`
+	Members []Player `json:"members"`
+}
+
+func Single(p Player) Entrant {
+	if p.Name == "" {
+		return Entrant{}
+	}
+	return Entrant{Members: []Player{p}}
+}
+
+func (e Entrant) IsTeam() bool {
+	return len(e.Members) > 1
+}
+
+// Country returns the members' country if they all share the same one.
+func (e Entrant) Country() string {
+	country := ""
+	for i, m := range e.Members {
+		if i > 0 && m.Country != country {
+			return ""
+		}
+		country = m.Country
+	}
+	return country
+}
+
+// Sponsor returns a single player's team. Teams' members may have different
+// sponsors, so they have none.
+func (e Entrant) Sponsor() string {
+	if len(e.Members) != 1 {
+		return ""
+	}
+	return e.Members[0].Team
+}
+
+// SameMembers reports whether both entrants are made of the same players,
+// in the same order.
+func (e Entrant) SameMembers(other Entrant) bool {
+	if len(e.Members) != len(other.Members) {
+		return false
+	}
+	for i := range e.Members {
+		if e.Members[i].Name != other.Members[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+// NameFormat decides how a team entrant's combined name is displayed.
+// Single players are always displayed by their own name.
+type NameFormat string
+
+const (
+	// "A / B"
+	MemberNames NameFormat = "names"
+	// "Team", or "A / B" if the team has no name.
+	TeamName NameFormat = "team"
+	// "Team (A / B)", or "A / B" if the team has no name.
+	TeamAndMemberNames NameFormat = "team+names"
+)
+
+var NameFormats = []NameFormat{MemberNames, TeamName, TeamAndMemberNames}
+
+func ParseNameFormat(s string) (NameFormat, error) {
+	for _, f := range NameFormats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("invalid name format: %q", s)
+}
+
+func (f NameFormat) Name(e Entrant) string {
+	names := make([]string, len(e.Members))
+	for i, m := range e.Members {
+		names[i] = m.Name
+	}
+	joined := strings.Join(names, " / ")
+	if !e.IsTeam() || e.Team == "" {
+		return joined
+	}
+	switch f {
+	case TeamName:
+		return e.Team
+	case TeamAndMemberNames:
+		return fmt.Sprintf("%s (%s)", e.Team, joined)
+	default:
+		return joined
+	}
 }
 
 // FromFile attempts to read players from csv file.
 // If file does not exist, it returns an empty slice.
-func FromFile(filepath string) []Player {
+func FromFile(filepath string) ([]Player, error) {
 	players := make([]Player, 0)
 
 	f, err := os.Open(filepath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return players, nil
+	}
 	if err != nil {
-		return players
+		return players, fmt.Errorf("load players: %w", err)
 	}
 	defer f.Close()
 
 	reader := csv.NewReader(f)
 	reader.FieldsPerRecord = 3
 	records, err := reader.ReadAll()
-	// TODO: should probably return error so GUI can show an error message
-	// instead of crashing.
 	if err != nil {
-		log.Fatalf("csv parse error for %s: %s", filepath, err)
+		return players, fmt.Errorf("load players from %s: %w", filepath, err)
 	}
 
 	for _, record := range records {
@@ -44,7 +143,7 @@ func FromFile(filepath string) []Player {
 		players = append(players, p)
 	}
 
-	return players
+	return players, nil
 }
 
 var nonAlphanumeric = regexp.MustCompile(`[^a-zA-Z0-9]+`)
@@ -71,5 +170,8 @@ func Write(filepath string, ps []Player) error {
 		writer.Write([]string{p.Name, p.Country, p.Team})
 	}
 	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("write players to file: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// FieldGroup is a set of scoreboard fields that a preset may restore.
+type FieldGroup string
+
+const (
+	// Description and subtitle
+	HeaderGroup FieldGroup = "header"
+	// Names, countries, teams and characters
+	PlayersGroup    FieldGroup = "players"
+	ScoresGroup     FieldGroup = "scores"
+	StageGroup      FieldGroup = "stage"
+	CommentaryGroup FieldGroup = "commentary"
+	// Everything defined in FieldsFile
+	CustomGroup FieldGroup = "custom"
+)
+
+var FieldGroups = []FieldGroup{
+	HeaderGroup, PlayersGroup, ScoresGroup, StageGroup, CommentaryGroup,
+	CustomGroup,
+}
+
+func ParseFieldGroups(s []string) ([]FieldGroup, error) {
+	result := make([]FieldGroup, 0, len(s))
+	for _, name := range s {
+		g := FieldGroup(name)
+		valid := false
+		for _, known := range FieldGroups {
+			valid = valid || g == known
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid field group: %q", name)
+		}
+		result = append(result, g)
+	}
+	return result, nil
+}
+
+// Preset is a saved scoreboard, of which only Groups are restored, e.g. to
+// set up the same commentators without touching the players.
+type Preset struct {
+	Name       string       `json:"name"`
+	Groups     []FieldGroup `json:"groups"`
+	Scoreboard Scoreboard   `json:"scoreboard"`
+}
+
+// ApplyTo copies the preset's groups of fields onto sb.
+func (p *Preset) ApplyTo(sb *Scoreboard) {
+	src := p.Scoreboard
+	for _, g := range p.Groups {
+		switch g {
+		case HeaderGroup:
+			sb.Description = src.Description
+			sb.Subtitle = src.Subtitle
+		case PlayersGroup:
+			sb.P1name, sb.P2name = src.P1name, src.P2name
+			sb.P1country, sb.P2country = src.P1country, src.P2country
+			sb.P1team, sb.P2team = src.P1team, src.P2team
+			sb.P1character, sb.P2character = src.P1character, src.P2character
+		case ScoresGroup:
+			sb.P1score, sb.P2score = src.P1score, src.P2score
+		case StageGroup:
+			sb.Stage = src.Stage
+		case CommentaryGroup:
+			sb.C1Title, sb.C1Subtitle = src.C1Title, src.C1Subtitle
+			sb.C2Title, sb.C2Subtitle = src.C2Title, src.C2Subtitle
+		case CustomGroup:
+			// Fields may have been added to the schema since the preset
+			// was saved, so those are left alone.
+			custom := make(map[string]string)
+			for name, value := range sb.Custom {
+				custom[name] = value
+			}
+			for name, value := range src.Custom {
+				custom[name] = value
+			}
+			sb.Custom = custom
+		}
+	}
+}
+
+// GroupNames returns e.g. "header commentary".
+func (p *Preset) GroupNames() string {
+	names := make([]string, len(p.Groups))
+	for i, g := range p.Groups {
+		names[i] = string(g)
+	}
+	return strings.Join(names, " ")
+}
+
+// Presets are kept in the order they were first saved.
+type Presets []Preset
+
+// LoadPresets returns an empty list if file does not exist.
+func LoadPresets(filepath string) (Presets, error) {
+	result := make(Presets, 0)
+	blob, err := os.ReadFile(filepath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("load presets: %w", err)
+	}
+	err = json.Unmarshal(blob, &result)
+	if err != nil {
+		return result, fmt.Errorf("load presets from %s: %w", filepath, err)
+	}
+	return result, nil
+}
+
+func (ps Presets) Write(filepath string) error {
+	blob, err := json.MarshalIndent(ps, "", "    ")
+	if err != nil {
+		return fmt.Errorf("write presets: %w", err)
+	}
+	err = os.WriteFile(filepath, blob, 0644)
+	if err != nil {
+		return fmt.Errorf("write presets: %w", err)
+	}
+	return nil
+}
+
+func (ps Presets) Find(name string) (Preset, bool) {
+	for _, p := range ps {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// Save returns a copy of ps with p added, or replacing the preset of the
+// same name.
+func (ps Presets) Save(p Preset) Presets {
+	result := make(Presets, 0, len(ps)+1)
+	replaced := false
+	for _, existing := range ps {
+		if existing.Name == p.Name {
+			existing, replaced = p, true
+		}
+		result = append(result, existing)
+	}
+	if !replaced {
+		result = append(result, p)
+	}
+	return result
+}
+
+func (ps Presets) Delete(name string) Presets {
+	result := make(Presets, 0, len(ps))
+	for _, p := range ps {
+		if p.Name != name {
+			result = append(result, p)
+		}
+	}
+	return result
+}
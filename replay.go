@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.imnhan.com/gorts/ipc"
+	"go.imnhan.com/gorts/players"
+)
+
+// ReplayMaxGap caps how long a replay waits between recorded requests, so
+// that a whole evening doesn't take a whole evening to replay.
+const ReplayMaxGap = 2 * time.Second
+
+// replayFiles are copied for a replay to start from the same data and
+// settings as now, without it touching the originals. Credentials are left
+// behind, including the legacy token file: replays never talk to tournament
+// sites, see refuseSites.
+var replayFiles = []string{
+	PlayersFile, CharactersFile, StagesFile, FieldsFile, RoundNamesFile,
+	PresetsFile, StartggFile, ChallongeFile, OfflineFile,
+	ScoreboardFile, BracketFile,
+}
+
+// enterReplay reads the session at path, then makes a temporary copy of
+// replayFiles the working directory. The scoreboard is the one from when the
+// session was recorded, if it has one.
+func enterReplay(path string) ([]ipc.Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	defer f.Close()
+	entries, err := ipc.ReadSession(f)
+	if err != nil {
+		return nil, fmt.Errorf("replay %s: %w", path, err)
+	}
+
+	dir, err := os.MkdirTemp("", "gorts-replay-")
+	if err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	for _, name := range replayFiles {
+		err := copyFile(name, filepath.Join(dir, name))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("replay: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dir, WebDir), 0755); err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	if len(entries) > 0 && entries[0].Dir == ipc.Snapshot {
+		err := os.WriteFile(ScoreboardFile, []byte(entries[0].Line), 0644)
+		if err != nil {
+			return nil, fmt.Errorf("replay: %w", err)
+		}
+	}
+	fmt.Printf("Replaying %s in %s\n", path, dir)
+	return entries, nil
+}
+
+// refuseSites fails every request, so that replaying a session doesn't e.g.
+// report its sets to start.gg all over again.
+type refuseSites struct{}
+
+func (refuseSites) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("not contacting %s during replay", req.URL.Host)
+}
+
+func copyFile(src, dst string) error {
+	blob, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, blob, 0644)
+}
+
+// replaySession feeds the GUI's side of entries to a GUI server, printing the
+// full traffic, then the resulting scoreboard and every file written.
+func replaySession(
+	entries []ipc.Entry,
+	state *State,
+	catalog *Catalog,
+	nameFormat players.NameFormat,
+	loadErrs loadErrors,
+) {
+	// Lines are printed in full below instead.
+	ipc.Debug = false
+	start := time.Now()
+	printer := func(dir string) io.Writer {
+		return ipc.NewLineWriter(func(line string) {
+			fmt.Printf("%8.3fs %s %s\n", time.Since(start).Seconds(), dir, line)
+		})
+	}
+
+	in := io.TeeReader(ipc.Replay(entries, ReplayMaxGap), printer(ipc.Incoming))
+	gui := ipc.NewWriter(printer(ipc.Outgoing))
+	serve := newGUIServer(state, catalog, nameFormat, loadErrs, guiSites{
+		// In the replay directory, unlike the real ones.
+		startggTokenFile: "startgg-token",
+		challongeKeyFile: "challonge-key",
+		transport:        refuseSites{},
+	})
+	serve(in, gui)
+
+	blob, _ := json.MarshalIndent(state.Scoreboard(), "", "    ")
+	fmt.Printf("\nResulting scoreboard:\n%s\n\nFiles written:\n", blob)
+	filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err == nil && !info.ModTime().Before(start) {
+			fmt.Printf("    %s (%d bytes)\n", path, info.Size())
+		}
+		return nil
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	"go.imnhan.com/gorts/players"
+	"go.imnhan.com/gorts/tournament"
+)
+
+// resultFromScoreboard maps scoreboard sides to the match's entrants by
+// their members, since players may have been swapped on stream.
+func resultFromScoreboard(sb Scoreboard, match tournament.Match) (tournament.Result, error) {
+	if match.Id == "" {
+		return tournament.Result{}, fmt.Errorf(
+			"No match loaded. Load one from a tournament tab first.",
+		)
+	}
+
+	if !match.Ready() {
+		return tournament.Result{}, fmt.Errorf(
+			"%s is still waiting on TBD players.", match.RoundText,
+		)
+	}
+
+	var result tournament.Result
+	entrants := match.Entrants
+	switch {
+	case sb.P1entrant.SameMembers(entrants[0]) && sb.P2entrant.SameMembers(entrants[1]):
+		result = tournament.Result{
+			Match:      match,
+			Names:      [2]string{sb.P1name, sb.P2name},
+			Scores:     [2]int{sb.P1score, sb.P2score},
+			Characters: [2]string{sb.P1character, sb.P2character},
+		}
+	case sb.P1entrant.SameMembers(entrants[1]) && sb.P2entrant.SameMembers(entrants[0]):
+		result = tournament.Result{
+			Match:      match,
+			Names:      [2]string{sb.P2name, sb.P1name},
+			Scores:     [2]int{sb.P2score, sb.P1score},
+			Characters: [2]string{sb.P2character, sb.P1character},
+		}
+	default:
+		return tournament.Result{}, fmt.Errorf(
+			"Scoreboard players (%s vs %s) don't match the loaded match (%s vs %s).",
+			sb.P1name, sb.P2name,
+			players.MemberNames.Name(entrants[0]),
+			players.MemberNames.Name(entrants[1]),
+		)
+	}
+
+	if result.Winner() == -1 {
+		return tournament.Result{}, fmt.Errorf(
+			"Scores are tied at %d - %d, there's no winner to report.",
+			result.Scores[0], result.Scores[1],
+		)
+	}
+	return result, nil
+}
@@ -0,0 +1,184 @@
+package startgg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.imnhan.com/gorts/bracket"
+)
+
+// TournamentSlug extracts the tournament slug from whatever the user pasted:
+// a bare slug ("my-weekly-42"), a "tournament/my-weekly-42" slug, or any
+// start.gg URL under the tournament, e.g.
+// https://www.start.gg/tournament/my-weekly-42/event/sf6/overview
+func TournamentSlug(input string) string {
+	slug := strings.TrimSpace(input)
+	if i := strings.Index(slug, "://"); i != -1 {
+		slug = slug[i+3:]
+	}
+	parts := strings.Split(strings.Trim(slug, "/"), "/")
+	for i, part := range parts {
+		if part == "tournament" && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return parts[len(parts)-1]
+}
+
+// CheckToken makes the cheapest authenticated request possible to tell
+// whether token works, and returns the gamer tag of its owner.
+func (c *Client) CheckToken(ctx context.Context) (string, error) {
+	query := `
+query CurrentUser {
+  currentUser {
+    id
+    player {
+      gamerTag
+    }
+  }
+}
+`
+	data := struct {
+		CurrentUser *struct {
+			Player *struct {
+				GamerTag string `json:"gamerTag"`
+			} `json:"player"`
+		} `json:"currentUser"`
+	}{}
+	err := c.Query(ctx, query, struct{}{}, &data)
+	if err != nil {
+		return "", fmt.Errorf("check token: %w", err)
+	}
+	if data.CurrentUser == nil {
+		return "", fmt.Errorf("Token is not linked to any start.gg user")
+	}
+	if data.CurrentUser.Player == nil {
+		return "", nil
+	}
+	return data.CurrentUser.Player.GamerTag, nil
+}
+
+// Phase is a stage of an event, e.g. "Pools" or "Top 8", made of one or more
+// phase groups. Each phase group is a bracket of its own.
+type Phase struct {
+	Id          string
+	Name        string
+	State       bracket.State
+	PhaseGroups []PhaseGroup
+}
+
+type PhaseGroup struct {
+	Id string
+	// e.g. "A1", or "1" for phases with only one group.
+	Identifier  string
+	BracketType bracket.Type
+	State       bracket.State
+}
+
+// GroupName returns a human-readable name of one of the phase's groups, e.g.
+// "Pools - A1", or just "Top 8" if the phase has only one group.
+func (p Phase) GroupName(g PhaseGroup) string {
+	if len(p.PhaseGroups) <= 1 || g.Identifier == "" {
+		return p.Name
+	}
+	return p.Name + " - " + g.Identifier
+}
+
+type PhasesVariables struct {
+	EventId string `json:"eventId"`
+}
+
+// Start.gg allows a few hundred pools per phase, but nobody streams those.
+const PhaseGroupsPerPhase = 128
+
+// FetchPhases lists the phases of an event, each with its phase groups, so
+// that the user can pick a bracket instead of digging its id out of a URL.
+func (c *Client) FetchPhases(ctx context.Context, eventId string) ([]Phase, error) {
+	query := fmt.Sprintf(`
+query EventPhases($eventId: ID!) {
+  event(id: $eventId) {
+    phases {
+      id
+      name
+      state
+      phaseGroups(query: {page: 1, perPage: %d}) {
+        nodes {
+          id
+          displayIdentifier
+          bracketType
+          state
+        }
+      }
+    }
+  }
+}
+`, PhaseGroupsPerPhase)
+	data := struct {
+		Event *struct {
+			Phases []struct {
+				Id          json.RawMessage `json:"id"`
+				Name        string          `json:"name"`
+				State       string          `json:"state"`
+				PhaseGroups *struct {
+					Nodes []struct {
+						Id                json.RawMessage `json:"id"`
+						DisplayIdentifier string          `json:"displayIdentifier"`
+						BracketType       string          `json:"bracketType"`
+						State             int             `json:"state"`
+					} `json:"nodes"`
+				} `json:"phaseGroups"`
+			} `json:"phases"`
+		} `json:"event"`
+	}{}
+	err := c.Query(ctx, query, PhasesVariables{EventId: eventId}, &data)
+	if err != nil {
+		return nil, fmt.Errorf("fetch phases: %w", err)
+	}
+	if data.Event == nil {
+		return nil, fmt.Errorf("Event %s not found", eventId)
+	}
+
+	phases := make([]Phase, 0)
+	for _, p := range data.Event.Phases {
+		phase := Phase{
+			Id:    unquoteId(p.Id),
+			Name:  p.Name,
+			State: phaseStates[p.State],
+		}
+		if p.PhaseGroups != nil {
+			for _, g := range p.PhaseGroups.Nodes {
+				phase.PhaseGroups = append(phase.PhaseGroups, PhaseGroup{
+					Id:          unquoteId(g.Id),
+					Identifier:  g.DisplayIdentifier,
+					BracketType: bracketType(g.BracketType),
+					State:       setStates[g.State],
+				})
+			}
+		}
+		phases = append(phases, phase)
+	}
+	return phases, nil
+}
+
+// Phases' state is the name of an ActivityState rather than its number,
+// see https://developer.start.gg/reference/activitystate.doc
+var phaseStates = map[string]bracket.State{
+	"CREATED":   bracket.Pending,
+	"ACTIVE":    bracket.InProgress,
+	"COMPLETED": bracket.Completed,
+	"READY":     bracket.Pending,
+	"INVALID":   bracket.Pending,
+	"CALLED":    bracket.InProgress,
+	"QUEUED":    bracket.Pending,
+}
+
+// bracketType also keeps types that the overlay can't render (yet), e.g.
+// swiss, so that they can at least be shown to the user.
+func bracketType(raw string) bracket.Type {
+	if t, ok := bracketTypes[raw]; ok {
+		return t
+	}
+	return bracket.Type(strings.ToLower(raw))
+}
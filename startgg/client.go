@@ -0,0 +1,224 @@
+package startgg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultURL = "https://api.start.gg/gql/alpha"
+
+// Timeout for a single HTTP request. Retries get their own timeout.
+const DefaultTimeout = 30 * time.Second
+
+// Start.gg allows 80 requests per 60 seconds, so space requests out by at
+// least this much to stay under the limit even during long imports.
+const DefaultMinInterval = 60 * time.Second / 80
+
+// How many times to retry after a 429 before giving up.
+const DefaultMaxRetries = 4
+
+// Client talks to start.gg's GraphQL API. Its fields may be changed before
+// first use, e.g. to point BaseURL to a local test server.
+type Client struct {
+	BaseURL     string
+	Token       string
+	HTTPClient  *http.Client
+	MinInterval time.Duration
+	MaxRetries  int
+
+	mu          sync.Mutex
+	lastRequest time.Time
+	// Token may be changed by SetToken while requests are in flight.
+	tokenMu sync.Mutex
+}
+
+func NewClient(token string) *Client {
+	return &Client{
+		BaseURL:     DefaultURL,
+		Token:       token,
+		HTTPClient:  &http.Client{Timeout: DefaultTimeout},
+		MinInterval: DefaultMinInterval,
+		MaxRetries:  DefaultMaxRetries,
+	}
+}
+
+// SetToken changes the token that requests are sent with from now on. Unlike
+// setting Token directly, it's safe to call while requests are in flight.
+func (c *Client) SetToken(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.Token = token
+}
+
+func (c *Client) token() string {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.Token
+}
+
+// APIError is a non-200 response from start.gg.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("start.gg responded with status %d", e.StatusCode)
+	}
+	return e.Message
+}
+
+// GraphQLErrors is the errors array that start.gg may send along with a 200
+// response, e.g. for invalid queries or failed mutations.
+type GraphQLErrors []struct {
+	Message string `json:"message"`
+}
+
+func (e GraphQLErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ErrNoData means start.gg responded successfully but without any data.
+var ErrNoData = errors.New("start.gg returned no data")
+
+type graphQLRequest struct {
+	Query     string `json:"query"`
+	Variables any    `json:"variables"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+}
+
+// Query sends a GraphQL query or mutation then decodes the response's data
+// field into result. Rate-limited (429) requests are retried with backoff
+// until ctx is done or MaxRetries is reached.
+func (c *Client) Query(ctx context.Context, query string, variables any, result any) error {
+	body, err := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return fmt.Errorf("encode graphql request: %w", err)
+	}
+
+	var respdata []byte
+	for attempt := 0; ; attempt++ {
+		var retryAfter time.Duration
+		respdata, retryAfter, err = c.post(ctx, body)
+		if err == nil {
+			break
+		}
+		if retryAfter == 0 || attempt >= c.MaxRetries {
+			return err
+		}
+		if retryAfter < 0 {
+			// No Retry-After header: exponential backoff from 1s.
+			retryAfter = time.Second << attempt
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryAfter):
+		}
+	}
+
+	var resp graphQLResponse
+	err = json.Unmarshal(respdata, &resp)
+	if err != nil {
+		return fmt.Errorf("unexpected response: %s", respdata)
+	}
+	if len(resp.Errors) > 0 {
+		return resp.Errors
+	}
+	if len(resp.Data) == 0 || string(resp.Data) == "null" {
+		return ErrNoData
+	}
+	err = json.Unmarshal(resp.Data, result)
+	if err != nil {
+		return fmt.Errorf("unexpected response data: %w", err)
+	}
+	return nil
+}
+
+// post sends a single request. If it was rate-limited, retryAfter is how long
+// start.gg asked us to wait, or negative if it didn't say.
+func (c *Client) post(ctx context.Context, body []byte) (respdata []byte, retryAfter time.Duration, err error) {
+	if err := c.throttle(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Add("User-Agent", "GORTS/0.5")
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+c.token())
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error making API request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respdata, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error reading API response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		respJson := struct {
+			Message string `json:"message"`
+		}{}
+		if json.Unmarshal(respdata, &respJson) == nil {
+			apiErr.Message = respJson.Message
+		} else {
+			apiErr.Message = fmt.Sprintf(
+				"Unexpected %d response: %s", resp.StatusCode, respdata,
+			)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			retryAfter = -1
+			seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+			if err == nil && seconds > 0 {
+				retryAfter = time.Duration(seconds) * time.Second
+			}
+		}
+		return nil, retryAfter, apiErr
+	}
+
+	return respdata, 0, nil
+}
+
+// throttle waits until at least MinInterval has passed since the previous
+// request.
+func (c *Client) throttle(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	wait := time.Until(c.lastRequest.Add(c.MinInterval))
+	if wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	c.lastRequest = time.Now()
+	return nil
+}
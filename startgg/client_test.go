@@ -0,0 +1,172 @@
+package startgg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClient talks to handler instead of start.gg, without throttling.
+func testClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	c := NewClient("secret")
+	c.BaseURL = server.URL
+	c.MinInterval = 0
+	return c
+}
+
+func TestQuery(t *testing.T) {
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		w.Write([]byte(`{"data":{"currentUser":{"player":{"gamerTag":"Daigo"}}}}`))
+	})
+	var result struct {
+		CurrentUser struct {
+			Player struct {
+				GamerTag string `json:"gamerTag"`
+			} `json:"player"`
+		} `json:"currentUser"`
+	}
+	err := c.Query(context.Background(), "query", nil, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.CurrentUser.Player.GamerTag != "Daigo" {
+		t.Errorf("got %+v", result)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		body   string
+		check  func(error) bool
+	}{
+		{
+			"graphql errors", 200, `{"errors":[{"message":"a"},{"message":"b"}]}`,
+			func(err error) bool {
+				var gqlErr GraphQLErrors
+				return errors.As(err, &gqlErr) && err.Error() == "a; b"
+			},
+		},
+		{
+			"null data", 200, `{"data":null}`,
+			func(err error) bool { return errors.Is(err, ErrNoData) },
+		},
+		{
+			"not json", 200, `<html>`,
+			func(err error) bool { return err.Error() == "unexpected response: <html>" },
+		},
+		{
+			"invalid token", 401, `{"message":"Invalid authentication token"}`,
+			func(err error) bool {
+				var apiErr *APIError
+				return errors.As(err, &apiErr) && apiErr.StatusCode == 401 &&
+					err.Error() == "Invalid authentication token"
+			},
+		},
+		{
+			"server error", 502, `Bad Gateway`,
+			func(err error) bool {
+				var apiErr *APIError
+				return errors.As(err, &apiErr) && apiErr.StatusCode == 502
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			})
+			var result any
+			err := c.Query(context.Background(), "query", nil, &result)
+			if err == nil || !tc.check(err) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			// Only rate limiting is worth retrying.
+			if calls.Load() != 1 {
+				t.Errorf("sent %d requests, want 1", calls.Load())
+			}
+		})
+	}
+}
+
+func TestQueryRetriesRateLimited(t *testing.T) {
+	var calls atomic.Int32
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"data":{}}`))
+	})
+	var result any
+	if err := c.Query(context.Background(), "query", nil, &result); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("sent %d requests, want 2", calls.Load())
+	}
+}
+
+func TestQueryGivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	c.MaxRetries = 0
+	var result any
+	err := c.Query(context.Background(), "query", nil, &result)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("sent %d requests, want 1", calls.Load())
+	}
+}
+
+func TestQueryCancelledWhileWaiting(t *testing.T) {
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var result any
+	err := c.Query(ctx, "query", nil, &result)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want deadline exceeded", err)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{}}`))
+	})
+	c.MinInterval = 30 * time.Millisecond
+	start := time.Now()
+	var result any
+	for i := 0; i < 3; i++ {
+		if err := c.Query(context.Background(), "query", nil, &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("3 requests took %s, want at least 2 intervals", elapsed)
+	}
+}
@@ -0,0 +1,103 @@
+package startgg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.imnhan.com/gorts/bracket"
+	"go.imnhan.com/gorts/players"
+	"go.imnhan.com/gorts/tournament"
+)
+
+// Provider makes start.gg a tournament.Provider, using whatever the user
+// entered on the start.gg tab.
+type Provider struct {
+	Client *Client
+	Inputs Inputs
+	// Also report each player's current character for every game.
+	ReportCharacters bool
+}
+
+func (p *Provider) FetchPlayers(
+	ctx context.Context, progress func(fetched, total int),
+) ([]players.Player, error) {
+	return p.Client.FetchPlayers(ctx, p.Inputs.Slug, p.Inputs.EventIds, progress)
+}
+
+// FetchMatches returns the sets of every stream in the stream queue.
+func (p *Provider) FetchMatches(ctx context.Context) ([]tournament.Match, error) {
+	streams, err := p.Client.FetchStreamQueue(ctx, p.Inputs.Slug)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]tournament.Match, 0)
+	for _, stream := range streams {
+		matches = append(matches, stream.Sets...)
+	}
+	return matches, nil
+}
+
+func (p *Provider) FetchMatch(ctx context.Context, id string) (tournament.Match, error) {
+	set, err := p.Client.FetchSet(ctx, id)
+	if err != nil {
+		return tournament.Match{}, err
+	}
+	match := tournament.Match{
+		Id:         set.Id,
+		RoundText:  set.RoundText,
+		State:      set.State,
+		EntrantIds: set.EntrantIds,
+	}
+	for i, name := range set.Entrants {
+		match.Entrants[i] = players.Single(players.Player{Name: name})
+	}
+	return match, nil
+}
+
+func (p *Provider) FetchBracket(ctx context.Context) (bracket.Bracket, error) {
+	return p.Client.FetchBracket(ctx, p.Inputs.PhaseGroupId)
+}
+
+// Report sends the final score and winner. We don't know the order of
+// games, so the loser's wins are reported first.
+func (p *Provider) Report(ctx context.Context, result tournament.Result) error {
+	set := result.Match
+	winner := result.Winner()
+	if winner == -1 {
+		return fmt.Errorf("Scores are tied, there's no winner to report.")
+	}
+	loser := 1 - winner
+	report := SetReport{
+		SetId:    set.Id,
+		WinnerId: set.EntrantIds[winner],
+	}
+
+	characters := make(map[string]string)
+	if p.ReportCharacters {
+		details, err := p.Client.FetchSet(ctx, set.Id)
+		if err != nil {
+			return err
+		}
+		for slot, name := range result.Characters {
+			id, ok := details.Characters[strings.ToLower(name)]
+			if !ok {
+				return fmt.Errorf(
+					"Unknown character for %s on start.gg: %q",
+					result.Names[slot], name,
+				)
+			}
+			characters[set.EntrantIds[slot]] = id
+		}
+	}
+
+	for _, slot := range []int{loser, winner} {
+		for n := 0; n < result.Scores[slot]; n++ {
+			report.Games = append(report.Games, GameResult{
+				WinnerId:   set.EntrantIds[slot],
+				Characters: characters,
+			})
+		}
+	}
+	return p.Client.ReportSet(ctx, report)
+}
@@ -0,0 +1,166 @@
+package startgg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.imnhan.com/gorts/bracket"
+)
+
+// SetDetails is the current state of a set on start.gg, fetched right before
+// reporting it so we can tell whether it has been reported or changed since
+// it was loaded.
+type SetDetails struct {
+	Id         string
+	RoundText  string
+	State      bracket.State
+	EntrantIds [2]string
+	Entrants   [2]string
+	// Character names of the event's game, lowercased, to their ids.
+	Characters map[string]string
+}
+
+type SetVariables struct {
+	SetId string `json:"setId"`
+}
+
+func (c *Client) FetchSet(ctx context.Context, setId string) (SetDetails, error) {
+	query := `
+query SetDetails($setId: ID!) {
+  set(id: $setId) {
+    id
+    fullRoundText
+    state
+    slots {
+      entrant {
+        id
+        name
+      }
+    }
+    event {
+      videogame {
+        characters {
+          id
+          name
+        }
+      }
+    }
+  }
+}
+`
+	data := struct {
+		Set *struct {
+			Id            json.RawMessage `json:"id"`
+			FullRoundText string          `json:"fullRoundText"`
+			State         int             `json:"state"`
+			Slots         []struct {
+				Entrant *struct {
+					Id   json.RawMessage `json:"id"`
+					Name string          `json:"name"`
+				} `json:"entrant"`
+			} `json:"slots"`
+			Event struct {
+				Videogame struct {
+					Characters []struct {
+						Id   json.RawMessage `json:"id"`
+						Name string          `json:"name"`
+					} `json:"characters"`
+				} `json:"videogame"`
+			} `json:"event"`
+		} `json:"set"`
+	}{}
+	err := c.Query(ctx, query, SetVariables{SetId: setId}, &data)
+	if err != nil {
+		return SetDetails{}, fmt.Errorf("fetch set: %w", err)
+	}
+	set := data.Set
+	if set == nil {
+		return SetDetails{}, fmt.Errorf("Set %s not found on start.gg", setId)
+	}
+
+	result := SetDetails{
+		Id:         unquoteId(set.Id),
+		RoundText:  set.FullRoundText,
+		State:      setStates[set.State],
+		Characters: make(map[string]string),
+	}
+	for j, slot := range set.Slots {
+		if j >= len(result.Entrants) || slot.Entrant == nil {
+			continue
+		}
+		result.EntrantIds[j] = unquoteId(slot.Entrant.Id)
+		result.Entrants[j] = slot.Entrant.Name
+	}
+	for _, c := range set.Event.Videogame.Characters {
+		result.Characters[strings.ToLower(c.Name)] = unquoteId(c.Id)
+	}
+	return result, nil
+}
+
+type SetReport struct {
+	SetId    string
+	WinnerId string
+	// In the order they were played.
+	Games []GameResult
+}
+
+type GameResult struct {
+	WinnerId string
+	// Entrant id => character id. May be empty.
+	Characters map[string]string
+}
+
+type ReportVariables struct {
+	SetId    string         `json:"setId"`
+	WinnerId string         `json:"winnerId"`
+	GameData []gameDataJson `json:"gameData"`
+}
+
+type gameDataJson struct {
+	WinnerId   string          `json:"winnerId"`
+	GameNum    int             `json:"gameNum"`
+	Selections []selectionJson `json:"selections,omitempty"`
+}
+
+type selectionJson struct {
+	EntrantId   string `json:"entrantId"`
+	CharacterId string `json:"characterId"`
+}
+
+// ReportSet reports a set's winner and games using start.gg's
+// reportBracketSet mutation.
+func (c *Client) ReportSet(ctx context.Context, r SetReport) error {
+	query := `
+mutation ReportSet($setId: ID!, $winnerId: ID!, $gameData: [BracketSetGameDataInput]) {
+  reportBracketSet(setId: $setId, winnerId: $winnerId, gameData: $gameData) {
+    id
+  }
+}
+`
+	vars := ReportVariables{
+		SetId:    r.SetId,
+		WinnerId: r.WinnerId,
+		GameData: make([]gameDataJson, len(r.Games)),
+	}
+	for n, game := range r.Games {
+		g := gameDataJson{WinnerId: game.WinnerId, GameNum: n + 1}
+		for entrantId, characterId := range game.Characters {
+			g.Selections = append(g.Selections, selectionJson{
+				EntrantId:   entrantId,
+				CharacterId: characterId,
+			})
+		}
+		vars.GameData[n] = g
+	}
+
+	// Errors such as "set is already completed" come back as a 200 with an
+	// errors array, which Query turns into GraphQLErrors.
+	var data json.RawMessage
+	err := c.Query(ctx, query, vars, &data)
+	if err != nil {
+		return fmt.Errorf("report set: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package startgg
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.imnhan.com/gorts/tournament"
+)
+
+// Inputs are everything the user entered on the start.gg tab. All of it
+// except the token is saved to a JSON settings file. The token is saved to
+// its own file that only the current user can read, see DefaultTokenPath.
+type Inputs struct {
+	Token        string `json:"-"`
+	Slug         string `json:"slug"`
+	PhaseGroupId string `json:"phasegroupid"`
+	// Only import players from these events. Empty means all events.
+	EventIds []string `json:"eventids"`
+	// Name of the stream whose queue we load sets from.
+	// Empty means the first stream.
+	Stream string `json:"stream"`
+	// Takes precedence over rounds.csv, see tournament.RoundNames.
+	RoundNames tournament.RoundNames `json:"roundnames"`
+}
+
+// DefaultTokenPath is in the user's config directory rather than next to
+// the executable, which is often a shared or synced folder on stream PCs.
+func DefaultTokenPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "startgg-token"
+	}
+	return filepath.Join(dir, "gorts", "startgg-token")
+}
+
+// LoadInputs reads settings and token from their own files. If there are no
+// settings yet but there is a legacy file (token, slug, phase group id and
+// stream on separate lines), it's migrated to the new files then deleted,
+// since it holds the token in plain sight.
+func LoadInputs(settingsPath, tokenPath, legacyPath string) (Inputs, error) {
+	var result Inputs
+
+	blob, err := os.ReadFile(settingsPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return migrateInputs(legacyPath, settingsPath, tokenPath)
+	}
+	if err != nil {
+		return result, fmt.Errorf("load start.gg settings: %w", err)
+	}
+	err = json.Unmarshal(blob, &result)
+	if err != nil {
+		return result, fmt.Errorf("load start.gg settings from %s: %w", settingsPath, err)
+	}
+
+	token, err := os.ReadFile(tokenPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return result, fmt.Errorf("load start.gg token: %w", err)
+	}
+	result.Token = strings.TrimSpace(string(token))
+	return result, nil
+}
+
+func migrateInputs(legacyPath, settingsPath, tokenPath string) (Inputs, error) {
+	var result Inputs
+	file, err := os.Open(legacyPath)
+	if err != nil {
+		return result, nil
+	}
+
+	s := bufio.NewScanner(file)
+	s.Scan()
+	result.Token = s.Text()
+	s.Scan()
+	result.Slug = s.Text()
+	s.Scan()
+	result.PhaseGroupId = s.Text()
+	s.Scan()
+	result.Stream = s.Text()
+	file.Close()
+
+	err = result.Write(settingsPath, tokenPath)
+	if err != nil {
+		return result, fmt.Errorf("migrate %s: %w", legacyPath, err)
+	}
+	err = os.Remove(legacyPath)
+	if err != nil {
+		return result, fmt.Errorf("migrate %s: %w", legacyPath, err)
+	}
+	return result, nil
+}
+
+// Write saves settings, and the token if there is one. An empty token
+// deletes the token file.
+func (i *Inputs) Write(settingsPath, tokenPath string) error {
+	blob, err := json.MarshalIndent(i, "", "    ")
+	if err != nil {
+		return fmt.Errorf("write start.gg settings: %w", err)
+	}
+	err = os.WriteFile(settingsPath, blob, 0644)
+	if err != nil {
+		return fmt.Errorf("write start.gg settings: %w", err)
+	}
+
+	if i.Token == "" {
+		err = os.Remove(tokenPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("delete start.gg token: %w", err)
+		}
+		return nil
+	}
+	err = os.MkdirAll(filepath.Dir(tokenPath), 0700)
+	if err != nil {
+		return fmt.Errorf("write start.gg token: %w", err)
+	}
+	err = os.WriteFile(tokenPath, []byte(i.Token), 0600)
+	if err != nil {
+		return fmt.Errorf("write start.gg token: %w", err)
+	}
+	// WriteFile only sets permissions on new files.
+	err = os.Chmod(tokenPath, 0600)
+	if err != nil {
+		return fmt.Errorf("write start.gg token: %w", err)
+	}
+	return nil
+}
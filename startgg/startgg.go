@@ -1,69 +1,54 @@
 package startgg
 
 import (
-	"bufio"
-	"bytes"
+	"context"
 	_ "embed"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"os"
+	"strconv"
+	"strings"
 
+	"go.imnhan.com/gorts/bracket"
 	"go.imnhan.com/gorts/players"
+	"go.imnhan.com/gorts/tournament"
 )
 
-const STARTGG_URL = "https://api.start.gg/gql/alpha"
-
-type GraphQL struct {
-	Query     string   `json:"query"`
-	Variables struct{} `json:"variables"`
-}
-
-type Inputs struct {
-	Token string
-	Slug  string
-	PhaseGroupId string
-}
-
-func LoadInputs(filepath string) Inputs {
-	var result Inputs
-	file, err := os.Open(filepath)
-	if err != nil {
-		return result
-	}
-	defer file.Close()
-
-	s := bufio.NewScanner(file)
-	s.Scan()
-	result.Token = s.Text()
-	s.Scan()
-	result.Slug = s.Text()
-	s.Scan()
-	result.PhaseGroupId = s.Text()
-	return result
-}
-
-func (c *Inputs) Write(filepath string) {
-	blob := []byte(fmt.Sprintf("%s\n%s\n", c.Token, c.Slug))
-	err := ioutil.WriteFile(filepath, blob, 0644)
-	if err != nil {
-		panic(err)
-	}
+type PlayersVariables struct {
+	Slug    string `json:"slug"`
+	Page    int    `json:"page"`
+	PerPage int    `json:"perPage"`
 }
 
-// TODO: follow pagination
-func FetchPlayers(i Inputs) ([]players.Player, error) {
+// Start.gg rejects queries that may return more than 1000 objects in total,
+// and each participant comes with several nested objects, so we start with
+// this many participants per page then halve it whenever start.gg complains.
+// Must be a power of 2 so that halved page sizes always line up with what
+// we've already fetched.
+const PlayersPerPage = 256
+
+// FetchPlayers fetches every participant of a tournament, page by page.
+// If progress is not nil, it's called after each page with the number of
+// players fetched so far and the total number of players.
+// Any error aborts the whole import: we never return a partial list.
+func (c *Client) FetchPlayers(
+	ctx context.Context,
+	slug string,
+	eventIds []string,
+	progress func(fetched, total int),
+) ([]players.Player, error) {
+	slug = TournamentSlug(slug)
 	query := `
-{
-  tournament(slug: "%s") {
-    participants(query: {page: %d, perPage: 500}) {
+query TournamentParticipants($slug: String!, $page: Int!, $perPage: Int!) {
+  tournament(slug: $slug) {
+    participants(query: {page: $page, perPage: $perPage}) {
+      pageInfo {
+        total
+        totalPages
+      }
       nodes {
         entrants {
           event {
-            slug
-            name
+            id
           }
           team {
             name
@@ -81,105 +66,206 @@ func FetchPlayers(i Inputs) ([]players.Player, error) {
   }
 }
 `
-	body, err := json.Marshal(GraphQL{
-		Query:     fmt.Sprintf(query, i.Slug, 1),
-		Variables: struct{}{},
-	})
-	if err != nil {
-		panic(err)
-	}
-
-	req, err := http.NewRequest("POST", STARTGG_URL, bytes.NewBuffer(body))
-	if err != nil {
-		panic(err)
-	}
-	req.Header.Add("User-Agent", "GORTS/0.5")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", "Bearer "+i.Token)
+	// Keep raw participants until we're done so that len(results) can be
+	// used for pagination, even if some of them will be filtered out.
+	results := make([]participant, 0)
+	perPage := PlayersPerPage
+
+	for {
+		// Page sizes only ever get halved, so len(results) is always a
+		// multiple of perPage.
+		page := len(results)/perPage + 1
+		vars := PlayersVariables{Slug: slug, Page: page, PerPage: perPage}
+
+		var data playersData
+		err := c.Query(ctx, query, vars, &data)
+		if err != nil {
+			if isComplexityError(err.Error()) && perPage > 1 {
+				perPage /= 2
+				continue
+			}
+			return nil, fmt.Errorf("fetch players page %d: %w", page, err)
+		}
+		if data.Tournament == nil {
+			return nil, fmt.Errorf("Tournament %s not found", slug)
+		}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("fetch players: %w", err)
-	}
-	defer resp.Body.Close()
+		participants := data.Tournament.Participants
+		results = append(results, participants.Nodes...)
+		total := participants.PageInfo.Total
 
-	respdata, err := ioutil.ReadAll(resp.Body)
-	//fmt.Println(">>>>", string(respdata[:50]))
+		if progress != nil {
+			progress(len(results), total)
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		respJson := struct {
-			Message string `json:"message"`
-		}{}
-		err = json.Unmarshal(respdata, &respJson)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"Unexpected %d response: %s", resp.StatusCode, respdata,
-			)
+		if page >= participants.PageInfo.TotalPages || len(participants.Nodes) == 0 {
+			if len(results) != total {
+				return nil, fmt.Errorf(
+					"fetch players: expected %d players but got %d",
+					total, len(results),
+				)
+			}
+			return filterPlayers(results, eventIds), nil
 		}
-		return nil, errors.New(respJson.Message)
 	}
+}
 
-	respJson := struct {
-		Data struct {
-			Tournament struct {
-				Participants struct {
-					Nodes []struct {
-						// TODO: read team names from entrants too
-						GamerTag string `json:"gamerTag"`
-						Prefix   string `json:"prefix"`
-						User     struct {
-							Location struct {
-								Country string `json:"country"`
-							} `json:"location"`
-						} `json:"user"`
-					} `json:"nodes"`
-				} `json:"participants"`
-			} `json:"tournament"`
-		} `json:"data"`
-	}{}
+func isComplexityError(msg string) bool {
+	return strings.Contains(strings.ToLower(msg), "complexity")
+}
 
-	err = json.Unmarshal(respdata, &respJson)
-	if err != nil {
-		return nil, fmt.Errorf(
-			"Unexpected %d response: %s", resp.StatusCode, respdata,
-		)
-	}
+type playersData struct {
+	Tournament *struct {
+		Participants struct {
+			PageInfo struct {
+				Total      int `json:"total"`
+				TotalPages int `json:"totalPages"`
+			} `json:"pageInfo"`
+			Nodes []participant `json:"nodes"`
+		} `json:"participants"`
+	} `json:"tournament"`
+}
 
-	participants := respJson.Data.Tournament.Participants.Nodes
-	results := make([]players.Player, len(participants))
-	for i, part := range participants {
-		p := players.Player{}
+type participant struct {
+	Entrants []struct {
+		Event struct {
+			Id json.RawMessage `json:"id"`
+		} `json:"event"`
+		Team *struct {
+			Name string `json:"name"`
+		} `json:"team"`
+	} `json:"entrants"`
+	GamerTag string `json:"gamerTag"`
+	Prefix   string `json:"prefix"`
+	User     struct {
+		Location struct {
+			Country string `json:"country"`
+		} `json:"location"`
+	} `json:"user"`
+}
 
-		if part.Prefix == "" {
-			p.Name = part.GamerTag
-		} else {
-			p.Name = fmt.Sprintf("%s %s", part.Prefix, part.GamerTag)
+// filterPlayers converts participants who entered at least one of eventIds
+// into players, taking their team name from those events' entrants.
+// Empty eventIds means all events.
+func filterPlayers(participants []participant, eventIds []string) []players.Player {
+	results := make([]players.Player, 0)
+	for _, part := range participants {
+		entered := len(eventIds) == 0
+		team := ""
+		for _, entrant := range part.Entrants {
+			if len(eventIds) > 0 && !contains(eventIds, unquoteId(entrant.Event.Id)) {
+				continue
+			}
+			entered = true
+			if team == "" && entrant.Team != nil {
+				team = entrant.Team.Name
+			}
+		}
+		if entered {
+			p := part.toPlayer()
+			p.Team = team
+			results = append(results, p)
 		}
+	}
+	return results
+}
 
-		code, ok := countryNameToCode[part.User.Location.Country]
-		if ok {
-			p.Country = code
-		} else if code != "" {
-			fmt.Printf("*** Unknown country: %s\n", part.User.Location.Country)
+func contains(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
 		}
+	}
+	return false
+}
 
-		results[i] = p
+func (part participant) toPlayer() players.Player {
+	p := players.Player{}
+
+	if part.Prefix == "" {
+		p.Name = part.GamerTag
+	} else {
+		p.Name = fmt.Sprintf("%s %s", part.Prefix, part.GamerTag)
+	}
+
+	country := part.User.Location.Country
+	code, ok := countryNameToCode[country]
+	if ok {
+		p.Country = code
+	} else if country != "" {
+		fmt.Printf("*** Unknown country: %s\n", country)
 	}
 
-	return results, nil
+	return p
+}
+
+type Event struct {
+	Id          string
+	Name        string
+	NumEntrants int
+}
+
+type EventsVariables struct {
+	Slug string `json:"slug"`
 }
 
+// FetchEvents lists the events of a tournament, e.g. so that the user can
+// pick which ones to import players from.
+func (c *Client) FetchEvents(ctx context.Context, slug string) ([]Event, error) {
+	slug = TournamentSlug(slug)
+	query := `
+query TournamentEvents($slug: String!) {
+  tournament(slug: $slug) {
+    events {
+      id
+      name
+      numEntrants
+    }
+  }
+}
+`
+	data := struct {
+		Tournament *struct {
+			Events []struct {
+				Id          json.RawMessage `json:"id"`
+				Name        string          `json:"name"`
+				NumEntrants int             `json:"numEntrants"`
+			} `json:"events"`
+		} `json:"tournament"`
+	}{}
+	err := c.Query(ctx, query, EventsVariables{Slug: slug}, &data)
+	if err != nil {
+		return nil, fmt.Errorf("fetch events: %w", err)
+	}
+	if data.Tournament == nil {
+		return nil, fmt.Errorf("Tournament %s not found", slug)
+	}
+
+	events := make([]Event, 0)
+	for _, e := range data.Tournament.Events {
+		events = append(events, Event{
+			Id:          unquoteId(e.Id),
+			Name:        e.Name,
+			NumEntrants: e.NumEntrants,
+		})
+	}
+	return events, nil
+}
 
 type StreamQueueVariables struct {
-	TourneySlug string
+	TourneySlug string `json:"tourneySlug"`
 }
-type StreamQueueGraphQL struct {
-	Query     string   `json:"query"`
-	Variables struct {
-		TourneySlug string `json:"tourneySlug"`
-	} `json:"variables"`
+
+type Stream struct {
+	Name   string
+	Source string
+	Sets   []tournament.Match
 }
-func FetchLatestStreamQueue(i Inputs) (players.Player, players.Player, error) {
+
+// FetchStreamQueue returns every stream of the tournament's stream queue,
+// each with its queued sets in order.
+func (c *Client) FetchStreamQueue(ctx context.Context, slug string) ([]Stream, error) {
+	slug = TournamentSlug(slug)
 	query := `
 	query StreamQueueOnTournament($tourneySlug: String!) {
 		tournament(slug: $tourneySlug) {
@@ -190,9 +276,14 @@ func FetchLatestStreamQueue(i Inputs) (players.Player, players.Player, error) {
 			  streamName
 			}
 			sets {
+			  id
 			  fullRoundText
+			  totalGames
+			  state
 			  slots {
 				entrant {
+				  id
+				  name
 				  participants {
 					prefix
 					gamerTag
@@ -200,145 +291,167 @@ func FetchLatestStreamQueue(i Inputs) (players.Player, players.Player, error) {
 						location {
 						  country
 						}
-					  } 
+					  }
 				  }
 				}
 			  }
 			}
 		  }
 		}
-	  }	  
+	  }
 `
-	body, err := json.Marshal(StreamQueueGraphQL{
-		Query:     query,
-		Variables: struct{TourneySlug string "json:\"tourneySlug\""}{
-			TourneySlug: i.Slug,
-		},
-	})
-	if err != nil {
-		panic(err)
-	}
-
-	req, err := http.NewRequest("POST", STARTGG_URL, bytes.NewBuffer(body))
+	data := struct {
+		Tournament *struct {
+			StreamQueue []struct {
+				Stream struct {
+					StreamSource string `json:"streamSource"`
+					StreamName   string `json:"streamName"`
+				} `json:"stream"`
+				Sets []queuedSetJson `json:"sets"`
+			} `json:"streamQueue"`
+		} `json:"tournament"`
+	}{}
+	err := c.Query(ctx, query, StreamQueueVariables{TourneySlug: slug}, &data)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("fetch stream queue: %w", err)
 	}
-	req.Header.Add("User-Agent", "GORTS/0.5")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", "Bearer "+i.Token)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return players.Player{}, players.Player{}, fmt.Errorf("Error making API request: %w", err)
+	if data.Tournament == nil {
+		return nil, fmt.Errorf("Tournament %s not found", slug)
 	}
-	defer resp.Body.Close()
 
-	respdata, err := ioutil.ReadAll(resp.Body)
-	//fmt.Println(">>>>", string(respdata[:50]))
-
-	if resp.StatusCode != http.StatusOK {
-		respJson := struct {
-			Message string `json:"message"`
-		}{}
-		err = json.Unmarshal(respdata, &respJson)
-		if err != nil {
-			return players.Player{}, players.Player{}, fmt.Errorf(
-				"Unexpected %d response: %s", resp.StatusCode, respdata,
-			)
+	streams := make([]Stream, 0)
+	for _, q := range data.Tournament.StreamQueue {
+		stream := Stream{
+			Name:   q.Stream.StreamName,
+			Source: q.Stream.StreamSource,
 		}
-		return players.Player{}, players.Player{}, errors.New(respJson.Message)
+		for _, qs := range q.Sets {
+			set, ok := qs.toMatch(stream.Name)
+			if !ok {
+				continue
+			}
+			stream.Sets = append(stream.Sets, set)
+		}
+		streams = append(streams, stream)
 	}
+	return streams, nil
+}
 
-	respJson := struct {
-		Data struct {
-			Tournament struct {
-				StreamQueue []struct {
-					Sets []struct {
-						FullRoundText string `json:"fullRoundText"`
-						Slots []struct {
-							Entrant struct {
-								Participants []struct {
-									Prefix string `json:"prefix"`
-									GamerTag string `json:"gamerTag"`
-									User struct {
-										Location struct {
-											Country string `json:"country"`
-										} `json:"location"`
-									} `json:"user"`
-								} `json:"participants"`
-							} `json:"entrant"`
-						} `json:"slots"`
-					} `json:"sets"`
-				} `json:"streamQueue"`
-			} `json:"tournament"`
-		} `json:"data"`
-	}{}
+// queuedSetJson is a stream queue set as returned by start.gg. Anything here
+// may be null: slots of a set whose previous rounds haven't finished have no
+// entrant yet, and doubles entrants have more than one participant.
+type queuedSetJson struct {
+	Id            json.RawMessage `json:"id"`
+	FullRoundText string          `json:"fullRoundText"`
+	TotalGames    *int            `json:"totalGames"`
+	State         int             `json:"state"`
+	Slots         []*struct {
+		Entrant *struct {
+			Id           json.RawMessage `json:"id"`
+			Name         string          `json:"name"`
+			Participants []*struct {
+				Prefix   string `json:"prefix"`
+				GamerTag string `json:"gamerTag"`
+				User     *struct {
+					Location *struct {
+						Country string `json:"country"`
+					} `json:"location"`
+				} `json:"user"`
+			} `json:"participants"`
+		} `json:"entrant"`
+	} `json:"slots"`
+}
 
-	err = json.Unmarshal(respdata, &respJson)
-	if err != nil {
-		return players.Player{}, players.Player{}, fmt.Errorf(
-			"Unexpected %d response: %s", resp.StatusCode, err.Error(),
-		)
+// toMatch returns false if the set can't be used at all, i.e. it has no id.
+// Missing entrants are left empty, see tournament.Match.Ready.
+// Entrants with several participants become teams.
+func (s queuedSetJson) toMatch(stream string) (tournament.Match, bool) {
+	set := tournament.Match{
+		Id:        unquoteId(s.Id),
+		Stream:    stream,
+		RoundText: s.FullRoundText,
+		State:     setStates[s.State],
+	}
+	if set.Id == "" {
+		return tournament.Match{}, false
+	}
+	if s.TotalGames != nil {
+		set.TotalGames = *s.TotalGames
 	}
+	for j, slot := range s.Slots {
+		if j >= len(set.Entrants) || slot == nil || slot.Entrant == nil {
+			continue
+		}
+		entrant := slot.Entrant
 
-	if (len(respJson.Data.Tournament.StreamQueue) > 0){
-		playerOne := players.Player{
-			Team: respJson.Data.Tournament.StreamQueue[0].Sets[0].Slots[0].Entrant.Participants[0].Prefix,
-			Name: respJson.Data.Tournament.StreamQueue[0].Sets[0].Slots[0].Entrant.Participants[0].GamerTag,
+		var e players.Entrant
+		for _, part := range entrant.Participants {
+			if part == nil || part.GamerTag == "" {
+				continue
+			}
+			p := players.Player{Name: part.GamerTag, Team: part.Prefix}
+			if part.User != nil && part.User.Location != nil {
+				p.Country = countryNameToCode[part.User.Location.Country]
+			}
+			e.Members = append(e.Members, p)
 		}
-		playerOneCountry, ok := countryNameToCode[respJson.Data.Tournament.StreamQueue[0].Sets[0].Slots[0].Entrant.Participants[0].User.Location.Country]
-		if ok {
-			playerOne.Country = playerOneCountry
+		if len(e.Members) == 0 {
+			e = players.Single(players.Player{Name: entrant.Name})
 		}
-		
-		playerTwo := players.Player{
-			Team: respJson.Data.Tournament.StreamQueue[0].Sets[0].Slots[1].Entrant.Participants[0].Prefix,
-			Name: respJson.Data.Tournament.StreamQueue[0].Sets[0].Slots[1].Entrant.Participants[0].GamerTag,
-			Country: respJson.Data.Tournament.StreamQueue[0].Sets[0].Slots[1].Entrant.Participants[0].User.Location.Country,
+		// Teams without a name get their members' names joined by
+		// start.gg, which isn't worth keeping as a team name.
+		if e.IsTeam() && entrant.Name != players.MemberNames.Name(e) {
+			e.Team = entrant.Name
 		}
-		playerTwoCountry, ok := countryNameToCode[respJson.Data.Tournament.StreamQueue[0].Sets[0].Slots[1].Entrant.Participants[0].User.Location.Country]
-		if ok {
-			playerTwo.Country = playerTwoCountry
+
+		id := unquoteId(entrant.Id)
+		if id == "" || len(e.Members) == 0 {
+			continue
 		}
-	
-		return playerOne, playerTwo, nil
-	} else {
-		return players.Player{}, players.Player{}, fmt.Errorf("No match found in stream queue")
+		set.Entrants[j] = e
+		set.EntrantIds[j] = id
 	}
+	return set, true
 }
 
 type BracketVariables struct {
 	PhaseGroupId string `json:"phaseGroupId"`
-	Page string `json:"page"`
-	PerPage string `json:"perPage"`
-}
-type BracketGraphQL struct {
-	Query     string   `json:"query"`
-	Variables BracketVariables `json:"variables"`
-}
-type Bracket []struct {
-	Round string
-	PlayerOne struct {
-		Name string
-		Score string
-	}
-	PlayerTwo struct {
-		Name string
-		Score string
-	}
+	Page         int    `json:"page"`
+	PerPage      int    `json:"perPage"`
 }
-func FetchBracket(i Inputs) (Bracket, error) {
+
+// Number of sets to fetch per request. Each set has quite a few nested
+// fields, so this needs to stay well below start.gg's query complexity limit
+// of 1000 objects.
+const BracketPerPage = 40
+
+// FetchBracket fetches every set of a phase group, following pagination.
+func (c *Client) FetchBracket(ctx context.Context, phaseGroupId string) (bracket.Bracket, error) {
 	query := `
 	query PhaseGroupSets($phaseGroupId: ID!, $page: Int!, $perPage: Int!) {
 		phaseGroup(id: $phaseGroupId) {
+		  displayIdentifier
+		  bracketType
+		  phase {
+			name
+		  }
 		  sets(page: $page, perPage: $perPage, sortType: ROUND) {
 			pageInfo {
-			  total
+			  totalPages
 			}
 			nodes {
+			  id
+			  identifier
+			  round
 			  fullRoundText
+			  state
+			  winnerId
 			  slots {
+				prereqId
+				prereqType
+				prereqPlacement
 				entrant {
+				  id
 				  name
 				}
 				standing {
@@ -352,95 +465,163 @@ func FetchBracket(i Inputs) (Bracket, error) {
 			}
 		  }
 		}
-	  }	  
+	  }
 `
-	body, err := json.Marshal(BracketGraphQL{
-		Query:     query,
-		Variables: BracketVariables{
-			PhaseGroupId: i.PhaseGroupId,
-			Page: "1",
-			PerPage: "12",
-		},
-	})
-	if err != nil {
-		panic(err)
-	}
+	result := bracket.Bracket{}
 
-	req, err := http.NewRequest("POST", STARTGG_URL, bytes.NewBuffer(body))
-	if err != nil {
-		panic(err)
+	for page, totalPages := 1, 1; page <= totalPages; page++ {
+		vars := BracketVariables{
+			PhaseGroupId: phaseGroupId,
+			Page:         page,
+			PerPage:      BracketPerPage,
+		}
+
+		var data bracketData
+		err := c.Query(ctx, query, vars, &data)
+		if err != nil {
+			return bracket.Bracket{}, fmt.Errorf("fetch bracket: %w", err)
+		}
+
+		phaseGroup := data.PhaseGroup
+		if phaseGroup == nil {
+			return bracket.Bracket{}, fmt.Errorf(
+				"Phase group %s not found", phaseGroupId,
+			)
+		}
+		result.Name = phaseGroup.Phase.Name
+		if phaseGroup.DisplayIdentifier != "" {
+			result.Name += " - " + phaseGroup.DisplayIdentifier
+		}
+		result.Type = bracketTypes[phaseGroup.BracketType]
+		for _, node := range phaseGroup.Sets.Nodes {
+			result.Sets = append(result.Sets, node.toSet())
+		}
+		totalPages = phaseGroup.Sets.PageInfo.TotalPages
 	}
-	req.Header.Add("User-Agent", "GORTS/0.5")
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", "Bearer "+i.Token)
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return Bracket{}, fmt.Errorf("Error making API request: %w", err)
+	if len(result.Sets) == 0 {
+		return bracket.Bracket{}, fmt.Errorf(
+			"Phase group %s has no sets", phaseGroupId,
+		)
 	}
-	defer resp.Body.Close()
+	return result, nil
+}
 
-	respdata, err := ioutil.ReadAll(resp.Body)
-	//fmt.Println(">>>>", string(respdata[:50]))
+type bracketData struct {
+	PhaseGroup *struct {
+		DisplayIdentifier string `json:"displayIdentifier"`
+		BracketType       string `json:"bracketType"`
+		Phase             struct {
+			Name string `json:"name"`
+		} `json:"phase"`
+		Sets struct {
+			PageInfo struct {
+				TotalPages int `json:"totalPages"`
+			} `json:"pageInfo"`
+			Nodes []bracketSet `json:"nodes"`
+		} `json:"sets"`
+	} `json:"phaseGroup"`
+}
 
-	if resp.StatusCode != http.StatusOK {
-		respJson := struct {
-			Message string `json:"message"`
-		}{}
-		err = json.Unmarshal(respdata, &respJson)
-		if err != nil {
-			return Bracket{}, fmt.Errorf(
-				"Unexpected %d response: %s", resp.StatusCode, respdata,
-			)
-		}
-		return Bracket{}, errors.New(respJson.Message)
+type bracketSet struct {
+	// Set IDs are usually numbers, but sets of a bracket that hasn't
+	// started yet have string IDs like "preview_123_1_0".
+	Id            json.RawMessage `json:"id"`
+	Identifier    string          `json:"identifier"`
+	Round         int             `json:"round"`
+	FullRoundText string          `json:"fullRoundText"`
+	State         int             `json:"state"`
+	WinnerId      *int            `json:"winnerId"`
+	Slots         []struct {
+		PrereqId        string `json:"prereqId"`
+		PrereqType      string `json:"prereqType"`
+		PrereqPlacement int    `json:"prereqPlacement"`
+		Entrant         *struct {
+			Id   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"entrant"`
+		Standing *struct {
+			Stats struct {
+				Score struct {
+					Value *int `json:"value"`
+				} `json:"score"`
+			} `json:"stats"`
+		} `json:"standing"`
+	} `json:"slots"`
+}
+
+var bracketTypes = map[string]bracket.Type{
+	"SINGLE_ELIMINATION": bracket.SingleElimination,
+	"DOUBLE_ELIMINATION": bracket.DoubleElimination,
+	"ROUND_ROBIN":        bracket.RoundRobin,
+}
+
+// See https://developer.start.gg/reference/activitystate.doc
+var setStates = map[int]bracket.State{
+	1: bracket.Pending,    // CREATED
+	2: bracket.InProgress, // ACTIVE
+	3: bracket.Completed,  // COMPLETED
+	4: bracket.Pending,    // READY
+	5: bracket.Pending,    // INVALID
+	6: bracket.InProgress, // CALLED
+	7: bracket.Pending,    // QUEUED
+}
+
+func (s bracketSet) toSet() bracket.Set {
+	result := bracket.Set{
+		ID:         unquoteId(s.Id),
+		Identifier: s.Identifier,
+		Round:      s.Round,
+		RoundText:  s.FullRoundText,
+		State:      setStates[s.State],
+	}
+	if result.State == "" {
+		result.State = bracket.Pending
 	}
 
-	respJson := struct {
-		Data struct {
-			PhaseGroup struct {
-				Sets struct {
-					Nodes []struct {
-						FullRoundText string `json:"fullRoundText"`
-						Slots []struct {
-							Entrant struct {
-								Name string `json:"name"`
-							} `json:"entrant"`
-							Standing struct {
-								Stats struct {
-									Score struct {
-										Value int `json:"value"`
-									} `json:"score"`
-								} `json:"stats"`
-							} `json:"standing"`
-						} `json:"slots"`
-					} `json:"nodes"`
-				} `json:"sets"`
-			} `json:"phaseGroup"`
-		} `json:"data"`
-	}{}
+	switch {
+	case s.Round < 0:
+		result.Side = bracket.Losers
+	case s.FullRoundText == "Grand Final Reset":
+		result.Side = bracket.GrandFinal
+		result.Reset = true
+	case s.FullRoundText == "Grand Final":
+		result.Side = bracket.GrandFinal
+	default:
+		result.Side = bracket.Winners
+	}
 
-	err = json.Unmarshal(respdata, &respJson)
-	if err != nil {
-		return Bracket{}, fmt.Errorf(
-			"Unexpected %d response: %s", resp.StatusCode, err.Error(),
-		)
+	for i, slot := range s.Slots {
+		if i >= len(result.Slots) {
+			break
+		}
+		r := &result.Slots[i]
+		if slot.PrereqType == "set" {
+			r.PrereqSetID = slot.PrereqId
+			if slot.PrereqPlacement == 2 {
+				r.PrereqPlacement = bracket.Loser
+			} else {
+				r.PrereqPlacement = bracket.Winner
+			}
+		}
+		if slot.Entrant != nil {
+			r.EntrantID = strconv.Itoa(slot.Entrant.Id)
+			r.Entrant = slot.Entrant.Name
+			r.Winner = s.WinnerId != nil && *s.WinnerId == slot.Entrant.Id
+		}
+		if slot.Standing != nil {
+			r.Score = slot.Standing.Stats.Score.Value
+		}
 	}
-	bracket := Bracket{}
-
-	for i := 0; i< len(respJson.Data.PhaseGroup.Sets.Nodes); i++ {
-		match := respJson.Data.PhaseGroup.Sets.Nodes[i]
-		bracket = append(bracket, struct{Round string; PlayerOne struct{Name string; Score string}; PlayerTwo struct{Name string; Score string}}{
-			Round: match.FullRoundText,
-			PlayerOne: struct{Name string; Score string}{
-				Name: match.Slots[0].Entrant.Name,
-				Score: fmt.Sprintf("%d",match.Slots[0].Standing.Stats.Score.Value),
-			},
-			PlayerTwo: struct{Name string; Score string}{
-				Name: match.Slots[1].Entrant.Name,
-				Score: fmt.Sprintf("%d",match.Slots[1].Standing.Stats.Score.Value),
-			},
-		})
+	return result
+}
+
+// unquoteId turns a json ID that may be either a number or a string into a
+// plain string.
+func unquoteId(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
 	}
-	return bracket, nil
+	return string(raw)
 }
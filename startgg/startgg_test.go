@@ -0,0 +1,91 @@
+package startgg
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.imnhan.com/gorts/bracket"
+)
+
+func decodeSet(t *testing.T, raw string) bracket.Set {
+	t.Helper()
+	var s bracketSet
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		t.Fatal(err)
+	}
+	return s.toSet()
+}
+
+func TestToSet(t *testing.T) {
+	set := decodeSet(t, `{
+		"id": 123, "identifier": "C", "round": -2,
+		"fullRoundText": "Losers Round 2", "state": 3, "winnerId": 8,
+		"slots": [
+			{"prereqId": "100", "prereqType": "set", "prereqPlacement": 2,
+			 "entrant": {"id": 7, "name": "A"},
+			 "standing": {"stats": {"score": {"value": 1}}}},
+			{"prereqId": "101", "prereqType": "set", "prereqPlacement": 1,
+			 "entrant": {"id": 8, "name": "B"},
+			 "standing": {"stats": {"score": {"value": 3}}}}
+		]
+	}`)
+	if set.ID != "123" || set.Side != bracket.Losers || set.State != bracket.Completed {
+		t.Errorf("got %+v", set)
+	}
+	p1, p2 := set.Slots[0], set.Slots[1]
+	if p1.PrereqSetID != "100" || p1.PrereqPlacement != bracket.Loser {
+		t.Errorf("slot 1 prereq = %s %s, want loser of 100", p1.PrereqPlacement, p1.PrereqSetID)
+	}
+	if p2.PrereqPlacement != bracket.Winner {
+		t.Errorf("slot 2 prereq = %s, want winner", p2.PrereqPlacement)
+	}
+	if p1.EntrantID != "7" || p1.Entrant != "A" || p1.Winner || *p1.Score != 1 {
+		t.Errorf("slot 1 = %+v", p1)
+	}
+	if !p2.Winner || *p2.Score != 3 || set.Winner() != 1 {
+		t.Errorf("slot 2 = %+v, want the winner", p2)
+	}
+}
+
+func TestToSetPreview(t *testing.T) {
+	// Brackets that haven't started have string IDs, no entrants in later
+	// rounds, and seeds rather than sets as prereqs in the first round.
+	set := decodeSet(t, `{
+		"id": "preview_123_1_0", "round": 3, "fullRoundText": "Grand Final Reset",
+		"state": 99,
+		"slots": [
+			{"prereqId": "5", "prereqType": "seed", "entrant": null},
+			{"prereqType": "bye"},
+			{"prereqType": "extra slot, ignored"}
+		]
+	}`)
+	if set.ID != "preview_123_1_0" {
+		t.Errorf("ID = %q", set.ID)
+	}
+	if set.Side != bracket.GrandFinal || !set.Reset {
+		t.Errorf("side = %s, reset = %t, want grand final reset", set.Side, set.Reset)
+	}
+	if set.State != bracket.Pending {
+		t.Errorf("unknown state = %q, want pending", set.State)
+	}
+	for i, slot := range set.Slots {
+		if slot.PrereqSetID != "" || slot.EntrantID != "" || slot.Score != nil {
+			t.Errorf("slot %d = %+v, want empty", i, slot)
+		}
+	}
+	if set.Winner() != -1 {
+		t.Errorf("winner = %d, want none", set.Winner())
+	}
+}
+
+func TestUnquoteId(t *testing.T) {
+	for raw, want := range map[string]string{
+		`123`:           "123",
+		`"123"`:         "123",
+		`"preview_1_0"`: "preview_1_0",
+	} {
+		if got := unquoteId(json.RawMessage(raw)); got != want {
+			t.Errorf("unquoteId(%s) = %q, want %q", raw, got, want)
+		}
+	}
+}
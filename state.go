@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"go.imnhan.com/gorts/players"
+)
+
+// How many applied scoreboards can be undone.
+const MaxUndo = 50
+
+var ErrNothingToUndo = errors.New("Nothing to undo.")
+var ErrNothingToRedo = errors.New("Nothing to redo.")
+
+// ErrNotSaved is wrapped by errors from writing an applied scoreboard to
+// disk. The scoreboard is still applied, and pushed to subscribers.
+var ErrNotSaved = errors.New("applied, but not saved")
+
+// State is the in-memory copy of the scoreboard that is currently on stream.
+// Every change goes through Apply, which persists it to disk and pushes it to
+// all subscribers (e.g. overlays connected via server-sent events).
+type State struct {
+	mu          sync.Mutex
+	scoreboard  Scoreboard
+	subscribers map[chan Scoreboard]struct{}
+	nameFormat  players.NameFormat
+	// Custom fields that every applied scoreboard must have.
+	schema Schema
+	// Optional log of every applied scoreboard.
+	history *History
+	// Previously applied scoreboards, most recent last, and those undone
+	// since the last apply.
+	undo []Scoreboard
+	redo []Scoreboard
+}
+
+func NewState(
+	scoreboard Scoreboard,
+	nameFormat players.NameFormat,
+	schema Schema,
+	history *History,
+) *State {
+	scoreboard.syncEntrants(scoreboard, nameFormat)
+	// Fields may have been removed from the schema since the scoreboard
+	// was saved, which is no reason to lose the others.
+	custom := make(map[string]string)
+	for name, value := range scoreboard.Custom {
+		if _, ok := schema.Field(name); ok {
+			custom[name] = value
+		}
+	}
+	custom, err := schema.Normalize(custom)
+	if err != nil {
+		fmt.Printf("Resetting custom fields: %s\n", err)
+		custom = schema.Defaults()
+	}
+	scoreboard.Custom = custom
+	return &State{
+		scoreboard:  scoreboard,
+		subscribers: make(map[chan Scoreboard]struct{}),
+		nameFormat:  nameFormat,
+		schema:      schema,
+		history:     history,
+	}
+}
+
+func (s *State) History() *History {
+	return s.history
+}
+
+func (s *State) Schema() Schema {
+	return s.schema
+}
+
+func (s *State) Scoreboard() Scoreboard {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scoreboard
+}
+
+func (s *State) Apply(scoreboard Scoreboard) (Scoreboard, error) {
+	return s.Update(func(sb *Scoreboard) error {
+		*sb = scoreboard
+		return nil
+	})
+}
+
+// Update applies fn to a copy of the current scoreboard then applies the
+// result, all while holding the lock, so that concurrent control surfaces
+// (GUI, HTTP API) never overwrite each other's changes. If fn returns an
+// error, nothing is applied. Failing to save it is an ErrNotSaved.
+func (s *State) Update(fn func(*Scoreboard) error) (Scoreboard, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scoreboard := s.scoreboard
+	if err := fn(&scoreboard); err != nil {
+		return s.scoreboard, err
+	}
+	custom, err := s.schema.Normalize(scoreboard.Custom)
+	if err != nil {
+		return s.scoreboard, err
+	}
+	scoreboard.Custom = custom
+	scoreboard.syncEntrants(s.scoreboard, s.nameFormat)
+	// Applying the same thing twice shouldn't take two undos to get past.
+	if !reflect.DeepEqual(scoreboard, s.scoreboard) {
+		s.undo = pushBounded(s.undo, s.scoreboard)
+		s.redo = nil
+	}
+	return scoreboard, s.apply(scoreboard)
+}
+
+// Undo goes back to the previously applied scoreboard.
+func (s *State) Undo() (Scoreboard, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.undo) == 0 {
+		return s.scoreboard, ErrNothingToUndo
+	}
+	scoreboard := s.undo[len(s.undo)-1]
+	s.undo = s.undo[:len(s.undo)-1]
+	s.redo = append(s.redo, s.scoreboard)
+	return scoreboard, s.apply(scoreboard)
+}
+
+// Redo reapplies the last undone scoreboard, unless something else has been
+// applied since.
+func (s *State) Redo() (Scoreboard, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.redo) == 0 {
+		return s.scoreboard, ErrNothingToRedo
+	}
+	scoreboard := s.redo[len(s.redo)-1]
+	s.redo = s.redo[:len(s.redo)-1]
+	s.undo = pushBounded(s.undo, s.scoreboard)
+	return scoreboard, s.apply(scoreboard)
+}
+
+// apply persists scoreboard and notifies subscribers. Caller must hold the
+// lock.
+func (s *State) apply(scoreboard Scoreboard) error {
+	s.scoreboard = scoreboard
+	err := s.scoreboard.Write()
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrNotSaved, err)
+	}
+	if s.history != nil {
+		if err := s.history.Append(scoreboard); err != nil {
+			fmt.Printf("Error: %s\n", err)
+		}
+	}
+	for ch := range s.subscribers {
+		notify(ch, scoreboard)
+	}
+	return err
+}
+
+// pushBounded appends scoreboard to stack, dropping the oldest entries past
+// MaxUndo.
+func pushBounded(stack []Scoreboard, scoreboard Scoreboard) []Scoreboard {
+	stack = append(stack, scoreboard)
+	if len(stack) > MaxUndo {
+		stack = append(stack[:0], stack[len(stack)-MaxUndo:]...)
+	}
+	return stack
+}
+
+// Subscribe returns a channel that immediately receives the current
+// scoreboard, then every subsequently applied one. Slow subscribers only ever
+// see the latest scoreboard: intermediate values are dropped.
+// Call the returned func to unsubscribe.
+func (s *State) Subscribe() (<-chan Scoreboard, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan Scoreboard, 1)
+	ch <- s.scoreboard
+	s.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subscribers, ch)
+	}
+	return ch, unsubscribe
+}
+
+// notify replaces any pending value in ch with scoreboard without blocking.
+func notify(ch chan Scoreboard, scoreboard Scoreboard) {
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- scoreboard
+}
@@ -0,0 +1,163 @@
+# Just enough JSON for the IPC protocol, since tcllib isn't always around.
+#
+# Tcl values don't know their own type, so encoding is explicit: encode each
+# string with json_encode_string, then put the results together with
+# json_encode_array and json_encode_object. Decoding gives objects as dicts,
+# arrays as lists, true/false as 1/0, null as "" and numbers as is.
+
+# Control characters must be escaped, the rest of unicode can go as is.
+set json_escapes [list "\\" "\\\\" "\"" "\\\"" "\n" "\\n" "\r" "\\r" "\t" "\\t"]
+for {set i 0} {$i < 0x20} {incr i} {
+    set c [format %c $i]
+    if {[lsearch -exact {"\n" "\r" "\t"} $c] == -1} {
+        lappend json_escapes $c [format "\\u%04x" $i]
+    }
+}
+
+proc json_encode_string {s} {
+    return "\"[string map $::json_escapes $s]\""
+}
+
+# Takes a list of already encoded values.
+proc json_encode_array {values} {
+    return "\[[join $values ,]\]"
+}
+
+# Takes a dict of keys to already encoded values.
+proc json_encode_object {d} {
+    set fields {}
+    dict for {key value} $d {
+        lappend fields "[json_encode_string $key]:$value"
+    }
+    return "\{[join $fields ,]\}"
+}
+
+# Convenience for the most common case: a dict of strings.
+proc json_encode_strings {d} {
+    set encoded {}
+    dict for {key value} $d {
+        dict set encoded $key [json_encode_string $value]
+    }
+    return [json_encode_object $encoded]
+}
+
+proc json_decode {text} {
+    set pos 0
+    set value [json__value $text pos]
+    json__skipspace $text pos
+    if {$pos != [string length $text]} {
+        error "json: unexpected data at $pos"
+    }
+    return $value
+}
+
+proc json__skipspace {text posvar} {
+    upvar 1 $posvar pos
+    if {[regexp -start $pos -indices {\A[ \t\r\n]+} $text match]} {
+        set pos [expr {[lindex $match 1] + 1}]
+    }
+}
+
+proc json__value {text posvar} {
+    upvar 1 $posvar pos
+    json__skipspace $text pos
+    switch -- [string index $text $pos] {
+        "\{" {
+            return [json__object $text pos]
+        }
+        "\[" {
+            return [json__array $text pos]
+        }
+        "\"" {
+            return [json__string $text pos]
+        }
+    }
+    foreach {literal value} {true 1 false 0 null ""} {
+        set end [expr {$pos + [string length $literal] - 1}]
+        if {[string range $text $pos $end] == $literal} {
+            set pos [expr {$end + 1}]
+            return $value
+        }
+    }
+    set number {\A-?(?:0|[1-9][0-9]*)(?:\.[0-9]+)?(?:[eE][+-]?[0-9]+)?}
+    if {[regexp -start $pos -indices $number $text match]} {
+        set pos [expr {[lindex $match 1] + 1}]
+        return [string range $text {*}$match]
+    }
+    error "json: unexpected character at $pos"
+}
+
+proc json__string {text posvar} {
+    upvar 1 $posvar pos
+    if {![regexp -start $pos -indices {\A"((?:[^"\\]|\\.)*)"} $text match inner]} {
+        error "json: unterminated string at $pos"
+    }
+    set pos [expr {[lindex $match 1] + 1}]
+    # JSON escapes are a subset of Tcl's, except for \/ which Tcl turns
+    # into / anyway.
+    return [subst -nocommands -novariables [string range $text {*}$inner]]
+}
+
+proc json__array {text posvar} {
+    upvar 1 $posvar pos
+    incr pos ;# \[
+    set result {}
+    json__skipspace $text pos
+    if {[string index $text $pos] == "\]"} {
+        incr pos
+        return $result
+    }
+    while 1 {
+        lappend result [json__value $text pos]
+        json__skipspace $text pos
+        switch -- [string index $text $pos] {
+            , {
+                incr pos
+            }
+            "\]" {
+                incr pos
+                return $result
+            }
+            default {
+                error "json: expected , or \] at $pos"
+            }
+        }
+    }
+}
+
+proc json__object {text posvar} {
+    upvar 1 $posvar pos
+    incr pos ;# \{
+    set result [dict create]
+    json__skipspace $text pos
+    if {[string index $text $pos] == "\}"} {
+        incr pos
+        return $result
+    }
+    while 1 {
+        json__skipspace $text pos
+        if {[string index $text $pos] != "\""} {
+            error "json: expected string key at $pos"
+        }
+        set key [json__string $text pos]
+        json__skipspace $text pos
+        if {[string index $text $pos] != ":"} {
+            error "json: expected : at $pos"
+        }
+        incr pos
+        dict set result $key [json__value $text pos]
+        json__skipspace $text pos
+        switch -- [string index $text $pos] {
+            , {
+                incr pos
+            }
+            "\}" {
+                incr pos
+                return $result
+            }
+            default {
+                error "json: expected , or \} at $pos"
+            }
+        }
+    }
+}
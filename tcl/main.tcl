@@ -8,7 +8,12 @@ foreach p {stdin stdout stderr} {
     fconfigure $p -translation lf
 }
 
-package require Tk
+# Without Tk, tclsh would carry on reading commands with no window to show.
+if {[catch {package require Tk} err]} {
+    puts stderr $err
+    exit 1
+}
+source -encoding "utf-8" [file join [file dirname [info script]] json.tcl]
 
 wm title . "Overly Repetitive Tedious Software (in Go)"
 tk appname gorts
@@ -74,18 +79,117 @@ array set startgg {
     token ""
     slug ""
     phasegroupid ""
+    eventids ""
+    stream ""
+    reportcharacters 0
     msg ""
 }
+# Events of current tournament, as shown in the events listbox.
+# Players are only imported from selected events, or all if none is selected.
+set startgg_eventids {}
+set startgg_eventnames {}
+# Phase groups of selected events, as shown in the phase group combobox.
+set startgg_phasegroupids {}
+set startgg_phasegroupnames {}
+
+array set challonge {
+    apikey ""
+    tournament ""
+    stream ""
+    msg ""
+}
+
+array set offline {
+    name ""
+    stream ""
+    msg ""
+}
+# Bracket types as sent to Go, and as shown in the type combobox.
+set offline_types {double_elimination single_elimination round_robin}
+set offline_typenames {"Double elimination" "Single elimination" "Round robin"}
+
+# Tournament sites we import from and report to. Each has its own tab, its
+# own settings array named after it (e.g. ::startgg), and the same widget
+# names for the same actions (e.g. $tab.buttons.fetch).
+array set provider_tabs {
+    startgg .n.s
+    challonge .n.c
+    offline .n.o
+}
+# Widgets that are disabled while fetching players or bracket.
+array set provider_busy {
+    startgg {.n.s.buttons.fetch .n.s.buttons.clear .n.s.token.entry
+        .n.s.tournamentslug}
+    challonge {.n.c.buttons.fetch .n.c.buttons.clear .n.c.apikey
+        .n.c.tournament}
+    offline {.n.o.create.button}
+}
+# Ids of each provider's async requests, for its Cancel button.
+array set provider_requests {
+    startgg {}
+    challonge {}
+    offline {}
+}
+array set provider_refresh {
+    startgg .n.s.stream.refresh
+    challonge .n.c.queue.refresh
+    offline .n.o.queue.refresh
+}
+# Provider that the Main tab's "Get Next Match" and "Report Result" buttons
+# talk to: the one a match was last loaded from.
+set provider startgg
+# Matches of each provider, each as a list of:
+# stream matchid roundtext state p1name p1country p1team p2name p2country
+# p2team subtitle
+array set matches {startgg {} challonge {} offline {}}
+# Only matches of the selected stream, as shown in the queue listbox.
+array set matches_shown {startgg {} challonge {} offline {}}
+array set matches_labels {startgg {} challonge {} offline {}}
+
+array set presets {
+    name ""
+    msg ""
+}
+# Field groups as sent to Go, and their checkbutton labels.
+set preset_grouplabels {
+    header Header
+    players Players
+    scores Scores
+    stage Stage
+    commentary Commentary
+    custom "Custom fields"
+}
+array set preset_groups {
+    header 1
+    players 1
+    scores 1
+    stage 1
+    commentary 1
+    custom 1
+}
+# Saved presets, as returned by getpresets.
+set preset_names {}
+set preset_savedgroups {}
+set preset_labels {}
 
-# GUI has 2 tabs: Main (.n.m) and start.gg (.n.s)
+# GUI has 6 tabs: Main (.n.m), start.gg (.n.s), Challonge (.n.c),
+# Offline (.n.o), Lower Thirds (.n.l) and Presets (.n.p), plus Custom (.n.f)
+# if there are custom fields, which is built by loadfields.
 
 ttk::notebook .n
 ttk::frame .n.m -padding 5
 ttk::frame .n.s -padding 5
+ttk::frame .n.c -padding 5
+ttk::frame .n.o -padding 5
 ttk::frame .n.l -padding 5
+ttk::frame .n.p -padding 5
+ttk::frame .n.f -padding 5
 .n add .n.m -text Main
 .n add .n.s -text start.gg
+.n add .n.c -text Challonge
+.n add .n.o -text Offline
 .n add .n.l -text "Lower Thirds"
+.n add .n.p -text Presets
 grid .n -column 0 -row 0 -sticky NESW
 
 # Main tab:
@@ -120,6 +224,8 @@ ttk::combobox .n.m.stage.entry -textvariable scoreboard(stage) -width 35
 ttk::frame .n.m.buttons
 ttk::button .n.m.buttons.apply -text "▶ Apply" -command applyscoreboard
 ttk::button .n.m.buttons.discard -text "✖ Discard" -command discardscoreboard
+ttk::button .n.m.buttons.undo -text "⟲ Undo" -command {undoscoreboard undo}
+ttk::button .n.m.buttons.redo -text "⟳ Redo" -command {undoscoreboard redo}
 ttk::button .n.m.buttons.reset -text "↶ Reset scores" -command {
     set scoreboard(p1score) 0
     set scoreboard(p2score) 0
@@ -129,7 +235,7 @@ ttk::button .n.m.buttons.swap -text "⇄ Swap players" -command {
     # countries last.
     set p1country $scoreboard(p1country)
     set p2country $scoreboard(p2country)
-    foreach key {name score team} {
+    foreach key {name score team character} {
         set tmp $scoreboard(p1$key)
         set scoreboard(p1$key) $scoreboard(p2$key)
         set scoreboard(p2$key) $tmp
@@ -137,7 +243,9 @@ ttk::button .n.m.buttons.swap -text "⇄ Swap players" -command {
     set scoreboard(p1country) $p2country
     set scoreboard(p2country) $p1country
 }
-ttk::button .n.m.buttons.sggstreamqueue -text "Get Latest from StartGG" -command getstreamqueue
+ttk::button .n.m.buttons.next -text "Get Next Match" -command nextmatch
+ttk::button .n.m.buttons.report -text "⇪ Report Result" -command reportmatch
+ttk::button .n.m.buttons.export -text "⤓ Export Sets" -command exportsets
 ttk::label .n.m.status -textvariable mainstatus
 grid .n.m.description -row 0 -column 0 -sticky NESW -pady {0 5}
 grid .n.m.description.lbl -row 0 -column 0 -padx {0 5}
@@ -172,13 +280,18 @@ grid columnconfigure .n.m.stage 1 -weight 1
 grid .n.m.buttons -row 4 -column 0 -sticky W -pady {10 0}
 grid .n.m.buttons.apply -row 0 -column 0
 grid .n.m.buttons.discard -row 0 -column 1
-grid .n.m.buttons.reset -row 0 -column 2
-grid .n.m.buttons.swap -row 0 -column 3
-grid .n.m.buttons.sggstreamqueue -row 0 -column 4
+grid .n.m.buttons.undo -row 0 -column 2
+grid .n.m.buttons.redo -row 0 -column 3
+grid .n.m.buttons.reset -row 0 -column 4
+grid .n.m.buttons.swap -row 0 -column 5
+grid .n.m.buttons.next -row 0 -column 6
+grid .n.m.buttons.report -row 0 -column 7
+grid .n.m.buttons.export -row 0 -column 8
 grid .n.m.status -row 5 -column 0 -columnspan 5 -pady {10 0} -sticky EW
 grid columnconfigure .n.m.players 2 -pad 5
 grid columnconfigure .n.m.buttons 1 -pad 15
 grid columnconfigure .n.m.buttons 3 -pad 15
+grid columnconfigure .n.m.buttons 5 -pad 15
 grid rowconfigure .n.m.players 1 -pad 5
 grid rowconfigure .n.m.players 3 -pad 5
 
@@ -186,31 +299,181 @@ grid rowconfigure .n.m.players 3 -pad 5
 
 #.n select .n.s; # for debug only
 ttk::label .n.s.tokenlbl -text "Personal token: "
-ttk::entry .n.s.token -show * -textvariable startgg(token)
-ttk::label .n.s.tournamentlbl -text "Tournament slug: "
+ttk::frame .n.s.token
+ttk::entry .n.s.token.entry -show * -textvariable startgg(token)
+ttk::button .n.s.token.check -text "✓ Check token" -command checktoken
+ttk::label .n.s.tournamentlbl -text "Tournament slug or URL: "
 ttk::entry .n.s.tournamentslug -textvariable startgg(slug)
-ttk::label .n.s.phasegrouplbl -text "Phase group id: "
-ttk::entry .n.s.phasegroupid -textvariable startgg(phasegroupid)
+ttk::label .n.s.eventslbl -text "Events: "
+ttk::frame .n.s.events
+listbox .n.s.events.list -listvariable startgg_eventnames \
+    -selectmode multiple -exportselection 0 -height 4
+ttk::button .n.s.events.load -text "↻ Load events" -command fetchevents
+ttk::label .n.s.phasegrouplbl -text "Phase group: "
+ttk::frame .n.s.phasegroup
+ttk::combobox .n.s.phasegroup.name -state readonly
+ttk::button .n.s.phasegroup.load -text "↻ Load phase groups" \
+    -command fetchphasegroups
+bind .n.s.phasegroup.name <<ComboboxSelected>> {
+    set ::startgg(phasegroupid) [lindex $::startgg_phasegroupids \
+        [.n.s.phasegroup.name current]]
+}
+ttk::label .n.s.streamlbl -text "Stream: "
+ttk::frame .n.s.stream
+ttk::combobox .n.s.stream.name -textvariable startgg(stream) -state readonly
+ttk::button .n.s.stream.refresh -text "↻ Refresh queue" \
+    -command {fetchmatches startgg}
+bind .n.s.stream.name <<ComboboxSelected>> {
+    set resp [ipc "setstream" $::startgg(stream)]
+    if {[lindex $resp 0] == "err"} {
+        set ::startgg(msg) [lindex $resp 1]
+    }
+    showmatches startgg
+}
+ttk::label .n.s.queuelbl -text "Queue: "
+ttk::frame .n.s.queue
+listbox .n.s.queue.list -listvariable matches_labels(startgg) \
+    -exportselection 0 -height 5
+ttk::button .n.s.queue.load -text "▲ Load set" -command {loadmatch startgg}
+ttk::checkbutton .n.s.queue.reportcharacters -text "Report characters" \
+    -variable startgg(reportcharacters)
+bind .n.s.queue.list <Double-1> {loadmatch startgg}
 ttk::frame .n.s.buttons
-ttk::button .n.s.buttons.fetch -text "↓ Fetch players" -command fetchplayers
-ttk::button .n.s.buttons.bracket -text "↓ Fetch bracket" -command getbracket
+ttk::button .n.s.buttons.fetch -text "↓ Fetch players" \
+    -command {fetchplayers startgg}
+ttk::button .n.s.buttons.bracket -text "↓ Fetch bracket" \
+    -command {fetchbracket startgg}
 ttk::button .n.s.buttons.clear -text "✘ Clear" -command clearstartgg
+ttk::button .n.s.buttons.cancel -text "✖ Cancel" \
+    -command {cancelrequests startgg}
 ttk::label .n.s.msg -textvariable startgg(msg)
 
 grid .n.s.tokenlbl -row 0 -column 0 -sticky W
 grid .n.s.token -row 0 -column 1 -sticky EW
+grid .n.s.token.entry -row 0 -column 0 -sticky EW
+grid .n.s.token.check -row 0 -column 1 -padx {5 0}
+grid columnconfigure .n.s.token 0 -weight 1
 grid .n.s.tournamentlbl -row 1 -column 0 -sticky W
 grid .n.s.tournamentslug -row 1 -column 1 -sticky EW
-grid .n.s.phasegrouplbl -row 2 -column 0 -sticky W
-grid .n.s.phasegroupid -row 2 -column 1 -sticky EW
-grid .n.s.buttons -row 3 -column 1 -stick WE
+grid .n.s.eventslbl -row 2 -column 0 -sticky NW
+grid .n.s.events -row 2 -column 1 -sticky EW
+grid .n.s.events.list -row 0 -column 0 -sticky EW
+grid .n.s.events.load -row 0 -column 1 -sticky N -padx {5 0}
+grid columnconfigure .n.s.events 0 -weight 1
+grid .n.s.phasegrouplbl -row 3 -column 0 -sticky W
+grid .n.s.phasegroup -row 3 -column 1 -sticky EW
+grid .n.s.phasegroup.name -row 0 -column 0 -sticky EW
+grid .n.s.phasegroup.load -row 0 -column 1 -padx {5 0}
+grid columnconfigure .n.s.phasegroup 0 -weight 1
+grid .n.s.streamlbl -row 4 -column 0 -sticky W
+grid .n.s.stream -row 4 -column 1 -sticky EW
+grid .n.s.stream.name -row 0 -column 0 -sticky EW
+grid .n.s.stream.refresh -row 0 -column 1 -padx {5 0}
+grid columnconfigure .n.s.stream 0 -weight 1
+grid .n.s.queuelbl -row 5 -column 0 -sticky NW
+grid .n.s.queue -row 5 -column 1 -sticky EW
+grid .n.s.queue.list -row 0 -column 0 -sticky EW
+grid .n.s.queue.load -row 0 -column 1 -sticky NW -padx {5 0}
+grid .n.s.queue.reportcharacters -row 0 -column 1 -sticky SW -padx {5 0}
+grid columnconfigure .n.s.queue 0 -weight 1
+grid .n.s.buttons -row 6 -column 1 -stick WE
 grid .n.s.buttons.fetch -stick W
 grid .n.s.buttons.bracket -row 0 -column 1 -stick W -padx 5
 grid .n.s.buttons.clear -row 0 -column 2 -stick W -padx 5
-grid .n.s.msg -row 4 -column 1 -stick W
+grid .n.s.buttons.cancel -row 0 -column 3 -stick W -padx 5
+grid .n.s.msg -row 7 -column 1 -stick W
 grid columnconfigure .n.s 1 -weight 1
 grid rowconfigure .n.s 1 -pad 5
 grid rowconfigure .n.s 2 -pad 5
+grid rowconfigure .n.s 3 -pad 5
+grid rowconfigure .n.s 4 -pad 5
+grid rowconfigure .n.s 5 -pad 5
+
+# Challonge tab:
+
+ttk::label .n.c.apikeylbl -text "API key: "
+ttk::entry .n.c.apikey -show * -textvariable challonge(apikey)
+ttk::label .n.c.tournamentlbl -text "Tournament URL: "
+ttk::entry .n.c.tournament -textvariable challonge(tournament)
+ttk::label .n.c.queuelbl -text "Matches: "
+ttk::frame .n.c.queue
+listbox .n.c.queue.list -listvariable matches_labels(challonge) \
+    -exportselection 0 -height 5
+ttk::button .n.c.queue.refresh -text "↻ Refresh matches" \
+    -command {fetchmatches challonge}
+ttk::button .n.c.queue.load -text "▲ Load match" -command {loadmatch challonge}
+bind .n.c.queue.list <Double-1> {loadmatch challonge}
+ttk::frame .n.c.buttons
+ttk::button .n.c.buttons.fetch -text "↓ Fetch players" \
+    -command {fetchplayers challonge}
+ttk::button .n.c.buttons.bracket -text "↓ Fetch bracket" \
+    -command {fetchbracket challonge}
+ttk::button .n.c.buttons.clear -text "✘ Clear" -command clearchallonge
+ttk::button .n.c.buttons.cancel -text "✖ Cancel" \
+    -command {cancelrequests challonge}
+ttk::label .n.c.msg -textvariable challonge(msg)
+
+grid .n.c.apikeylbl -row 0 -column 0 -sticky W
+grid .n.c.apikey -row 0 -column 1 -sticky EW
+grid .n.c.tournamentlbl -row 1 -column 0 -sticky W
+grid .n.c.tournament -row 1 -column 1 -sticky EW
+grid .n.c.queuelbl -row 2 -column 0 -sticky NW
+grid .n.c.queue -row 2 -column 1 -sticky EW
+grid .n.c.queue.list -row 0 -column 0 -rowspan 2 -sticky EW
+grid .n.c.queue.refresh -row 0 -column 1 -sticky NEW -padx {5 0}
+grid .n.c.queue.load -row 1 -column 1 -sticky NEW -padx {5 0}
+grid columnconfigure .n.c.queue 0 -weight 1
+grid .n.c.buttons -row 3 -column 1 -stick WE
+grid .n.c.buttons.fetch -stick W
+grid .n.c.buttons.bracket -row 0 -column 1 -stick W -padx 5
+grid .n.c.buttons.clear -row 0 -column 2 -stick W -padx 5
+grid .n.c.buttons.cancel -row 0 -column 3 -stick W -padx 5
+grid .n.c.msg -row 4 -column 1 -stick W
+grid columnconfigure .n.c 1 -weight 1
+grid rowconfigure .n.c 1 -pad 5
+grid rowconfigure .n.c 2 -pad 5
+grid rowconfigure .n.c 3 -pad 5
+
+# Offline tab:
+
+ttk::label .n.o.namelbl -text "Tournament name: "
+ttk::entry .n.o.name -textvariable offline(name)
+ttk::label .n.o.typelbl -text "Type: "
+ttk::frame .n.o.create
+ttk::combobox .n.o.create.type -state readonly -values $offline_typenames
+.n.o.create.type current 0
+ttk::button .n.o.create.button -text "✚ Create from players.csv" \
+    -command createoffline
+ttk::label .n.o.queuelbl -text "Sets: "
+ttk::frame .n.o.queue
+listbox .n.o.queue.list -listvariable matches_labels(offline) \
+    -exportselection 0 -height 8
+ttk::button .n.o.queue.refresh -text "↻ Refresh sets" \
+    -command {fetchmatches offline}
+ttk::button .n.o.queue.load -text "▲ Load set" -command {loadmatch offline}
+ttk::button .n.o.queue.bracket -text "↓ Write bracket" \
+    -command {fetchbracket offline}
+bind .n.o.queue.list <Double-1> {loadmatch offline}
+ttk::label .n.o.msg -textvariable offline(msg)
+
+grid .n.o.namelbl -row 0 -column 0 -sticky W
+grid .n.o.name -row 0 -column 1 -sticky EW
+grid .n.o.typelbl -row 1 -column 0 -sticky W
+grid .n.o.create -row 1 -column 1 -sticky EW
+grid .n.o.create.type -row 0 -column 0 -sticky EW
+grid .n.o.create.button -row 0 -column 1 -padx {5 0}
+grid columnconfigure .n.o.create 0 -weight 1
+grid .n.o.queuelbl -row 2 -column 0 -sticky NW
+grid .n.o.queue -row 2 -column 1 -sticky EW
+grid .n.o.queue.list -row 0 -column 0 -rowspan 3 -sticky EW
+grid .n.o.queue.refresh -row 0 -column 1 -sticky NEW -padx {5 0}
+grid .n.o.queue.load -row 1 -column 1 -sticky NEW -padx {5 0}
+grid .n.o.queue.bracket -row 2 -column 1 -sticky NEW -padx {5 0}
+grid columnconfigure .n.o.queue 0 -weight 1
+grid .n.o.msg -row 3 -column 1 -stick W
+grid columnconfigure .n.o 1 -weight 1
+grid rowconfigure .n.o 1 -pad 5
+grid rowconfigure .n.o 2 -pad 5
 
 # Lower Thirds tab:
 
@@ -244,13 +507,60 @@ grid .n.l.c2subtitle.entry -row 1 -column 3 -sticky EW
 grid rowconfigure .n.l 1 -pad 5
 grid rowconfigure .n.l 2 -pad 5
 
+# Presets tab:
+
+ttk::label .n.p.listlbl -text "Presets: "
+ttk::frame .n.p.list
+listbox .n.p.list.box -listvariable preset_labels -exportselection 0 -height 8
+ttk::button .n.p.list.load -text "▲ Load preset" -command loadpreset
+ttk::button .n.p.list.delete -text "✖ Delete preset" -command deletepreset
+bind .n.p.list.box <Double-1> loadpreset
+bind .n.p.list.box <<ListboxSelect>> showpreset
+ttk::label .n.p.namelbl -text "Name: "
+ttk::entry .n.p.name -textvariable presets(name)
+ttk::label .n.p.groupslbl -text "Restores: "
+ttk::frame .n.p.groups
+foreach {group label} $preset_grouplabels {
+    ttk::checkbutton .n.p.groups.$group -text $label \
+        -variable preset_groups($group)
+}
+ttk::button .n.p.save -text "✚ Save current scoreboard" -command savepreset
+ttk::label .n.p.msg -textvariable presets(msg)
+
+grid .n.p.listlbl -row 0 -column 0 -sticky NW
+grid .n.p.list -row 0 -column 1 -sticky EW
+grid .n.p.list.box -row 0 -column 0 -rowspan 2 -sticky EW
+grid .n.p.list.load -row 0 -column 1 -sticky NEW -padx {5 0}
+grid .n.p.list.delete -row 1 -column 1 -sticky NEW -padx {5 0}
+grid columnconfigure .n.p.list 0 -weight 1
+grid .n.p.namelbl -row 1 -column 0 -sticky W
+grid .n.p.name -row 1 -column 1 -sticky EW
+grid .n.p.groupslbl -row 2 -column 0 -sticky W
+grid .n.p.groups -row 2 -column 1 -sticky W
+set i 0
+foreach {group _} $preset_grouplabels {
+    grid .n.p.groups.$group -row 0 -column $i -padx {0 10}
+    incr i
+}
+grid .n.p.save -row 3 -column 1 -sticky W
+grid .n.p.msg -row 4 -column 1 -stick W
+grid columnconfigure .n.p 1 -weight 1
+grid rowconfigure .n.p 1 -pad 5
+grid rowconfigure .n.p 2 -pad 5
+grid rowconfigure .n.p 3 -pad 5
+
 proc initialize {} {
     loadicon
     loadstartgg
+    loadchallonge
+    loadoffline
     loadwebmsg
+    loaderrors
+    loadfields
     loadcountrycodes
     loadscoreboard
     loadplayernames
+    loadpresets
 
     setupdiffcheck
     setupplayersuggestion
@@ -277,7 +587,12 @@ proc ipc_write {method args} {
 }
 proc ipc_read {} {
     set results {}
-    set numlines [gets stdin]
+    # Go may push commands (e.g. scoreboardchanged) at any time, even while
+    # we're waiting for a response, so defer anything that isn't a response
+    # header until we're done here.
+    while {![string is integer -strict [set numlines [gets stdin]]]} {
+        ipc_defer $numlines
+    }
     for {set i 0} {$i < $numlines} {incr i} {
         lappend results [gets stdin]
     }
@@ -288,6 +603,91 @@ proc ipc {method args} {
     return [ipc_read]
 }
 
+# Newer JSON protocol, one object per line, which takes named params as a
+# dict of strings. Returns the response's result, or raises its error.
+set ipc_version 1
+proc ipc_call {method {params {}}} {
+    puts [json_encode_object [dict create \
+        v $::ipc_version \
+        method [json_encode_string $method] \
+        params [json_encode_strings $params] \
+    ]]
+    set resp [ipc_readjson]
+    if {[dict exists $resp error]} {
+        error [dict get $resp error]
+    }
+    foreach key {result values} {
+        if {[dict exists $resp $key]} {
+            return [dict get $resp $key]
+        }
+    }
+    return {}
+}
+proc ipc_readjson {} {
+    # Same as ipc_read: defer commands until we get our response.
+    while {[string index [set line [gets stdin]] 0] != "\{"} {
+        ipc_defer $line
+    }
+    return [json_decode $line]
+}
+
+# Requests that take a while (anything talking to a tournament site) are
+# sent with an id and answered whenever they're done, so the GUI stays
+# usable in the meantime. The answer comes as an ipc_receive command, and is
+# passed to the request's callback as either "ok $result" or "err $error".
+set ipc_nextid 0
+array set ipc_callbacks {}
+proc ipc_async {method callback args} {
+    set id [incr ::ipc_nextid]
+    set ::ipc_callbacks($id) $callback
+    set encoded {}
+    foreach a $args {
+        lappend encoded [json_encode_string $a]
+    }
+    puts [json_encode_object [dict create \
+        v $::ipc_version \
+        id [json_encode_string $id] \
+        method [json_encode_string $method] \
+        args [json_encode_array $encoded] \
+    ]]
+    return $id
+}
+proc ipc_receive {} {
+    ipc_dispatch [gets stdin]
+}
+proc ipc_dispatch {line} {
+    set resp [json_decode $line]
+    set id [dict get $resp id]
+    if {![info exists ::ipc_callbacks($id)]} {
+        return
+    }
+    set callback $::ipc_callbacks($id)
+    unset ::ipc_callbacks($id)
+    if {[dict exists $resp error]} {
+        {*}$callback err [dict get $resp error]
+    } elseif {[dict exists $resp result]} {
+        {*}$callback ok [dict get $resp result]
+    } else {
+        {*}$callback ok {}
+    }
+}
+# Commands Go may push while we're waiting for a response.
+set ipc_pushed {scoreboardchanged fetchplayers__progress}
+# Runs a command that arrived while waiting for a response, once we're done.
+# An async response must be read right away though, or its JSON line would
+# be mistaken for ours. Anything else means we're out of sync with Go, so
+# the line is never evaluated: it may well be data, e.g. a player's name.
+proc ipc_defer {line} {
+    set words [split $line " "]
+    if {$line == "ipc_receive"} {
+        after idle [list ipc_dispatch [gets stdin]]
+    } elseif {[lindex $words 0] in $::ipc_pushed} {
+        after idle $words
+    } else {
+        puts stderr "Ignored unexpected line from Go: $line"
+    }
+}
+
 proc windows_forcefocus {} {
     # First call winapi's SetForegroundWindow()
     set handle [winfo id .]
@@ -311,6 +711,35 @@ proc loadstartgg {} {
     set ::startgg(token) [lindex $resp 0]
     set ::startgg(slug) [lindex $resp 1]
     set ::startgg(phasegroupid) [lindex $resp 2]
+    set ::startgg(stream) [lindex $resp 3]
+    set ::startgg(eventids) [lindex $resp 4]
+    showphasegroup
+}
+
+proc loadchallonge {} {
+    set resp [ipc "getchallonge"]
+    set ::challonge(apikey) [lindex $resp 0]
+    set ::challonge(tournament) [lindex $resp 1]
+    # Only default to Challonge if it's the only one set up.
+    if {$::startgg(slug) == "" && $::challonge(tournament) != ""} {
+        set ::provider challonge
+    }
+}
+
+proc loadoffline {} {
+    set resp [ipc "getoffline"]
+    if {[lindex $resp 0] != "ok"} {
+        set ::offline(msg) [lindex $resp 1]
+        return
+    }
+    set ::offline(name) [lindex $resp 1]
+    set i [lsearch -exact $::offline_types [lindex $resp 2]]
+    if {$i != -1} {
+        .n.o.create.type current $i
+    }
+    if {$::offline(name) != ""} {
+        fetchmatches offline
+    }
 }
 
 proc loadwebmsg {} {
@@ -319,55 +748,143 @@ proc loadwebmsg {} {
     set ::mainstatus "Point your OBS browser source to http://localhost:${webport}"
 }
 
+# Anything Go couldn't load at startup, e.g. a malformed players.csv, is
+# shown in place of the web message.
+proc loaderrors {} {
+    set errors [ipc "getloaderrors"]
+    if {[llength $errors] > 0} {
+        set ::mainstatus [join $errors "\n"]
+    }
+}
+
+# Errors that nothing else caught, e.g. from an ipc_call, go to the status
+# area instead of a dialog, so the GUI stays usable.
+proc bgerror {message} {
+    set ::mainstatus "Error: $message"
+    puts stderr $::errorInfo
+}
+
 proc loadcountrycodes {} {
     set codes [ipc "getcountrycodes"]
     .n.m.players.p1country configure -values $codes
     .n.m.players.p2country configure -values $codes
+    foreach widget $::custom_widgets(country) {
+        $widget configure -values $codes
+    }
+}
+
+# Custom field widgets of each type that takes suggestions.
+array set custom_widgets {country {} character {} player {}}
+
+# Builds the Custom tab from the fields defined in fields.csv, and adds them
+# to scoreboard_keys so they're loaded and applied like built-in ones.
+proc loadfields {} {
+    set fields [ipc "getfields"]
+    if {[llength $fields] == 0} {
+        return
+    }
+    set row 0
+    foreach {name type label} $fields {
+        set widget .n.f.$name
+        ttk::label .n.f.${name}lbl -text $label
+        switch $type {
+            score {
+                ttk::spinbox $widget -textvariable scoreboard($name) \
+                    -from 0 -to 999 -width 4
+            }
+            country {
+                ttk::combobox $widget -textvariable scoreboard($name) -width 5
+            }
+            character -
+            player {
+                ttk::combobox $widget -textvariable scoreboard($name) -width 35
+            }
+            default {
+                ttk::entry $widget -textvariable scoreboard($name)
+            }
+        }
+        if {[info exists ::custom_widgets($type)]} {
+            lappend ::custom_widgets($type) $widget
+        }
+        # Only text fields stretch, like Title and Subtitle on the Main tab.
+        set sticky W
+        if {[winfo class $widget] == "TEntry"} {
+            set sticky EW
+        }
+        grid .n.f.${name}lbl -row $row -column 0 -sticky W -padx {0 5}
+        grid $widget -row $row -column 1 -sticky $sticky -pady {0 5}
+        incr row
+
+        lappend ::scoreboard_keys $name
+        set ::var_to_widget($name) $widget
+        set ::scoreboard($name) ""
+        set ::applied_scoreboard($name) ""
+    }
+    grid columnconfigure .n.f 1 -weight 1
+    .n insert .n.p .n.f -text Custom
+}
+
+# Order of values returned by getscoreboard.
+# Country comes after name because it's updated whenever name is updated.
+set scoreboard_keys {
+    description subtitle stage
+    p1name p1country p1score p1team p1character
+    p2name p2country p2score p2team p2character
+    c1title c1subtitle c2title c2subtitle
 }
 
 proc loadscoreboard {} {
-    set sb [ipc "getscoreboard"]
-    set ::scoreboard(description) [lindex $sb 0]
-    set ::scoreboard(subtitle) [lindex $sb 1]
-    set ::scoreboard(p1name) [lindex $sb 2]
-    set ::scoreboard(p1country) [lindex $sb 3]
-    set ::scoreboard(p1score) [lindex $sb 4]
-    set ::scoreboard(p1team) [lindex $sb 5]
-    set ::scoreboard(p2name) [lindex $sb 6]
-    set ::scoreboard(p2country) [lindex $sb 7]
-    set ::scoreboard(p2score) [lindex $sb 8]
-    set ::scoreboard(p2team) [lindex $sb 9]
+    setscoreboard [ipc_call "getscoreboard"]
+}
+
+# Sets GUI fields from sb, a dict of scoreboard_keys, as applied.
+proc setscoreboard {sb} {
+    foreach key $::scoreboard_keys {
+        set ::scoreboard($key) [dict get $sb $key]
+    }
     update_applied_scoreboard
 }
 
+# Called by Go whenever the scoreboard is applied, which may be from somewhere
+# else e.g. the HTTP API. Fields with unapplied edits in the GUI are left
+# alone so the operator doesn't lose what they're typing.
+proc scoreboardchanged {} {
+    set sb [ipc_call "getscoreboard"]
+    set dirtykeys {}
+    foreach key $::scoreboard_keys {
+        if {$::scoreboard($key) != $::applied_scoreboard($key)} {
+            lappend dirtykeys $key
+        }
+    }
+    foreach key $::scoreboard_keys {
+        set value [dict get $sb $key]
+        if {[lsearch -exact $dirtykeys $key] == -1} {
+            set ::scoreboard($key) $value
+        }
+        set ::applied_scoreboard($key) $value
+    }
+}
+
 proc applyscoreboard {} {
-    set sb [ \
-        ipc "applyscoreboard" \
-        $::scoreboard(description) \
-        $::scoreboard(subtitle) \
-        $::scoreboard(stage) \
-        $::scoreboard(p1name) \
-        $::scoreboard(p1country) \
-        $::scoreboard(p1score) \
-        $::scoreboard(p1team) \
-        $::scoreboard(p1character) \
-        $::scoreboard(p2name) \
-        $::scoreboard(p2country) \
-        $::scoreboard(p2score) \
-        $::scoreboard(p2team) \
-        $::scoreboard(p2character) \
-        $::scoreboard(c1title) \
-        $::scoreboard(c1subtitle) \
-        $::scoreboard(c2title) \
-        $::scoreboard(c2subtitle) \
-    ]
-    update_applied_scoreboard
+    set params {}
+    foreach key $::scoreboard_keys {
+        dict set params $key $::scoreboard($key)
+    }
+    if {[catch {ipc_call "applyscoreboard" $params} sb]} {
+        set ::mainstatus "Error: $sb"
+        return
+    }
+    # Go may have cleaned up custom fields, e.g. " 02" to "2".
+    setscoreboard $sb
 }
 
 proc loadplayernames {} {
     set playernames [ipc "searchplayers" ""]
     .n.m.players.p1name configure -values $playernames
     .n.m.players.p2name configure -values $playernames
+    foreach widget $::custom_widgets(player) {
+        $widget configure -values $playernames
+    }
 }
 
 proc setupplayersuggestion {} {
@@ -394,110 +911,594 @@ proc setupcharacters {} {
     set characters [ipc "loadcharacters"]
     $widgetOne configure -values $characters
     $widgetTwo configure -values $characters
+    foreach widget $::custom_widgets(character) {
+        $widget configure -values $characters
+    }
 }
 proc setupstages {} {
     set widget .n.m.stage.entry
     set stages [ipc "loadstages"]
     $widget configure -values $stages
 }
-proc fetchplayers {} {
+# Settings sent along with every request to a provider, see useProvider in
+# main.go.
+proc providerargs {provider} {
+    switch $provider {
+        offline {
+            return {}
+        }
+        challonge {
+            return [list $::challonge(apikey) $::challonge(tournament)]
+        }
+        default {
+            return [list $::startgg(token) $::startgg(slug) \
+                $::startgg(phasegroupid) $::startgg(reportcharacters) \
+                {*}$::startgg(eventids)]
+        }
+    }
+}
+
+# Returns what the user still needs to enter before we can talk to provider,
+# or an empty string.
+proc providermissing {provider} {
+    switch $provider {
+        offline {}
+        challonge {
+            if {$::challonge(apikey) == "" || $::challonge(tournament) == ""} {
+                return "Please enter API key & tournament URL first."
+            }
+        }
+        default {
+            if {$::startgg(token) == "" || $::startgg(slug) == ""} {
+                return "Please enter token & slug first."
+            }
+        }
+    }
+    return ""
+}
+
+proc setbusy {provider busy} {
+    set state [expr {$busy ? "disabled" : "normal"}]
+    foreach widget $::provider_busy($provider) {
+        $widget configure -state $state
+    }
+}
+
+# Sends an async request on behalf of provider, so that its tab's Cancel
+# button can cancel it.
+proc providerrequest {provider method callback args} {
+    set id [ipc_async $method $callback {*}$args]
+    lappend ::provider_requests($provider) $id
+}
+
+proc cancelrequests {provider} {
+    upvar #0 $provider settings
+    set pending {}
+    foreach id $::provider_requests($provider) {
+        if {[info exists ::ipc_callbacks($id)]} {
+            lappend pending $id
+        }
+    }
+    set ::provider_requests($provider) $pending
+    if {$pending == {}} {
+        set settings(msg) "Nothing to cancel."
+        return
+    }
+    set settings(msg) "Cancelling..."
+    foreach id $pending {
+        ipc_call "cancel" [dict create id $id]
+    }
+}
+
+proc fetchplayers {provider} {
+    upvar #0 $provider settings
+    set missing [providermissing $provider]
+    if {$missing != ""} {
+        set settings(msg) $missing
+        return
+    }
+    setbusy $provider 1
+    set settings(msg) "Fetching..."
+    if {$provider == "startgg"} {
+        set ::startgg(eventids) [selectedeventids]
+    }
+    providerrequest $provider "fetchplayers" [list fetchplayers__resp $provider] \
+        $provider {*}[providerargs $provider]
+}
+
+proc fetchplayers__progress {provider fetched total} {
+    upvar #0 $provider settings
+    set settings(msg) "Fetching... $fetched/$total players"
+}
+
+proc fetchplayers__resp {provider status data} {
+    upvar #0 $provider settings
+    if {$status == "ok"} {
+        set settings(msg) [dict get $data msg]
+        loadplayernames
+    } else {
+        set settings(msg) $data
+    }
+    setbusy $provider 0
+}
+
+proc fetchevents {} {
     if {$::startgg(token) == "" || $::startgg(slug) == ""} {
         set ::startgg(msg) "Please enter token & slug first."
         return
     }
-    .n.s.buttons.fetch configure -state disabled
-    .n.s.buttons.clear configure -state disabled
-    .n.s.token configure -state disabled
-    .n.s.tournamentslug configure -state disabled
-    .n state disabled
-    set ::startgg(msg) "Fetching..."
-    ipc_write "fetchplayers" $::startgg(token) $::startgg(slug)
+    .n.s.events.load configure -state disabled
+    set ::startgg(msg) "Fetching events..."
+    providerrequest startgg "fetchevents" fetchevents__resp \
+        $::startgg(token) $::startgg(slug)
 }
 
-proc fetchplayers__resp {} {
-    set resp [ipc_read]
-    set status [lindex $resp 0]
-    set msg [lindex $resp 1]
+proc fetchevents__resp {status data} {
+    if {$status == "ok"} {
+        set ::startgg(msg) [dict get $data msg]
+        set ::startgg_eventids {}
+        set ::startgg_eventnames {}
+        foreach event [dict get $data events] {
+            lappend ::startgg_eventids [dict get $event id]
+            lappend ::startgg_eventnames [dict get $event name]
+        }
+        # Restore the events players were last imported from.
+        .n.s.events.list selection clear 0 end
+        foreach id $::startgg(eventids) {
+            set i [lsearch -exact $::startgg_eventids $id]
+            if {$i != -1} {
+                .n.s.events.list selection set $i
+            }
+        }
+    } else {
+        set ::startgg(msg) $data
+    }
 
-    set ::startgg(msg) $msg
+    .n.s.events.load configure -state normal
+}
+
+proc selectedeventids {} {
+    set ids {}
+    foreach i [.n.s.events.list curselection] {
+        lappend ids [lindex $::startgg_eventids $i]
+    }
+    return $ids
+}
 
+proc checktoken {} {
+    if {$::startgg(token) == ""} {
+        set ::startgg(msg) "Please enter token first."
+        return
+    }
+    .n.s.token.check configure -state disabled
+    set ::startgg(msg) "Checking token..."
+    providerrequest startgg "checktoken" checktoken__resp $::startgg(token)
+}
+
+proc checktoken__resp {status data} {
     if {$status == "ok"} {
-        loadplayernames
+        set ::startgg(msg) [dict get $data msg]
+    } else {
+        set ::startgg(msg) $data
     }
+    .n.s.token.check configure -state normal
+}
 
-    .n.s.buttons.fetch configure -state normal
-    .n.s.buttons.clear configure -state normal
-    .n.s.token configure -state normal
-    .n.s.tournamentslug configure -state normal
-    .n state !disabled
+# Phase groups are loaded from selected events, or all events if none is
+# selected.
+proc fetchphasegroups {} {
+    if {$::startgg(token) == ""} {
+        set ::startgg(msg) "Please enter token first."
+        return
+    }
+    set eventids [selectedeventids]
+    if {$eventids == {}} {
+        set eventids $::startgg_eventids
+    }
+    if {$eventids == {}} {
+        set ::startgg(msg) "Please load events first."
+        return
+    }
+    .n.s.phasegroup.load configure -state disabled
+    set ::startgg(msg) "Fetching phase groups..."
+    providerrequest startgg "fetchphasegroups" fetchphasegroups__resp \
+        $::startgg(token) {*}$eventids
+}
+
+proc fetchphasegroups__resp {status data} {
+    if {$status == "ok"} {
+        set ::startgg(msg) [dict get $data msg]
+        set ::startgg_phasegroupids {}
+        set ::startgg_phasegroupnames {}
+        foreach group [dict get $data groups] {
+            set eventid [dict get $group eventid]
+            set id [dict get $group id]
+            set name [dict get $group name]
+            set i [lsearch -exact $::startgg_eventids $eventid]
+            if {$i != -1} {
+                set name "[lindex $::startgg_eventnames $i]: $name"
+            }
+            lappend ::startgg_phasegroupids $id
+            lappend ::startgg_phasegroupnames $name
+        }
+        .n.s.phasegroup.name configure -values $::startgg_phasegroupnames
+        showphasegroup
+    } else {
+        set ::startgg(msg) $data
+    }
+
+    .n.s.phasegroup.load configure -state normal
+}
+
+# Shows the current phase group by name if it has been loaded, or by id.
+proc showphasegroup {} {
+    set id $::startgg(phasegroupid)
+    set i [lsearch -exact $::startgg_phasegroupids $id]
+    if {$i != -1} {
+        .n.s.phasegroup.name set [lindex $::startgg_phasegroupnames $i]
+    } elseif {$id != ""} {
+        .n.s.phasegroup.name set "Phase group $id"
+    } else {
+        .n.s.phasegroup.name set ""
+    }
 }
 
 proc clearstartgg {} {
     set ::startgg(token) ""
     set ::startgg(slug) ""
     set ::startgg(msg) ""
-    ipc_write "clearstartgg"
+    set ::startgg(phasegroupid) ""
+    set ::startgg(eventids) ""
+    set ::startgg_eventids {}
+    set ::startgg_eventnames {}
+    set ::startgg_phasegroupids {}
+    set ::startgg_phasegroupnames {}
+    .n.s.phasegroup.name configure -values {}
+    showphasegroup
+    set resp [ipc "clearstartgg"]
+    if {[lindex $resp 0] == "err"} {
+        set ::startgg(msg) [lindex $resp 1]
+    }
 }
-proc getstreamqueue {} {
-    if {$::startgg(token) == "" || $::startgg(slug) == ""} {
-        set ::startgg(msg) "Please enter token & slug first."
+
+# Seeds a new offline bracket from players.csv, in the file's order.
+proc createoffline {} {
+    set i [.n.o.create.type current]
+    set type [lindex $::offline_types $i]
+    if {[llength $::matches(offline)] > 0} {
+        set answer [tk_messageBox -type yesno -icon warning \
+            -title "Create offline bracket" \
+            -message "Replace the current offline bracket and all its results?"]
+        if {$answer != "yes"} {
+            return
+        }
+    }
+    set resp [ipc "createoffline" $::offline(name) $type]
+    set ::offline(msg) [lindex $resp 1]
+    if {[lindex $resp 0] == "ok"} {
+        set ::provider offline
+        fetchmatches offline
+    }
+}
+
+proc clearchallonge {} {
+    set ::challonge(apikey) ""
+    set ::challonge(tournament) ""
+    set ::challonge(msg) ""
+    set ::matches(challonge) {}
+    showmatches challonge
+    set resp [ipc "clearchallonge"]
+    if {[lindex $resp 0] == "err"} {
+        set ::challonge(msg) [lindex $resp 1]
+    }
+}
+
+# Loads the next match that's ready to play into the scoreboard, from the
+# selected stream if provider has streams.
+proc nextmatch {} {
+    set provider $::provider
+    set missing [providermissing $provider]
+    if {$missing != ""} {
+        set ::mainstatus $missing
+        return
+    }
+    .n.m.buttons.next configure -state disabled
+    set ::mainstatus "Fetching next match..."
+    providerrequest $provider "fetchnextmatch" nextmatch__resp \
+        $provider {*}[providerargs $provider]
+}
+
+proc nextmatch__resp {status data} {
+    if {$status == "ok"} {
+        set ::mainstatus [dict get $data msg]
+        set match [dict get $data match]
+        # Country is updated whenever player name is updated,
+        # so make sure we set countries last.
+        foreach key {p1name p1team p2name p2team subtitle p1country p2country} {
+            set ::scoreboard($key) [dict get $match $key]
+        }
+        set ::scoreboard(p1score) 0
+        set ::scoreboard(p2score) 0
+    } else {
+        set ::mainstatus $data
+    }
+
+    .n.m.buttons.next configure -state normal
+}
+
+proc fetchmatches {provider} {
+    upvar #0 $provider settings
+    set missing [providermissing $provider]
+    if {$missing != ""} {
+        set settings(msg) $missing
         return
     }
-    .n.s.buttons.fetch configure -state disabled
-    .n.s.buttons.clear configure -state disabled
-    .n.s.token configure -state disabled
-    .n.s.tournamentslug configure -state disabled
-    set ::startgg(msg) "Fetching..."
-    ipc_write "fetchlateststreamqueue" $::startgg(token) $::startgg(slug)
+    $::provider_refresh($provider) configure -state disabled
+    set settings(msg) "Fetching matches..."
+    providerrequest $provider "fetchmatches" [list fetchmatches__resp $provider] \
+        $provider {*}[providerargs $provider]
 }
-proc getstreamqueue__resp {} {
-    set resp [ipc_read]
-    set status [lindex $resp 0]
-    set msg [lindex $resp 1]
 
-    set ::startgg(msg) $msg
+# Fields of a match, in the order they're kept in ::matches.
+set match_keys {stream id roundtext state p1name p1country p1team p2name
+    p2country p2team subtitle}
 
+proc fetchmatches__resp {provider status data} {
+    upvar #0 $provider settings
     if {$status == "ok"} {
-        set ::scoreboard(p1name) [lindex $resp 2]
-        set ::scoreboard(p1country) [lindex $resp 3]
-        set ::scoreboard(p1score) [lindex $resp 4]
-        set ::scoreboard(p1team) [lindex $resp 5]
-        set ::scoreboard(p2name) [lindex $resp 6]
-        set ::scoreboard(p2country) [lindex $resp 7]
-        set ::scoreboard(p2score) [lindex $resp 8]
-        set ::scoreboard(p2team) [lindex $resp 9]
+        set settings(msg) [dict get $data msg]
+        set ::matches($provider) {}
+        set streamnames {}
+        foreach m [dict get $data matches] {
+            set match {}
+            foreach key $::match_keys {
+                lappend match [dict get $m $key]
+            }
+            lappend ::matches($provider) $match
+            set stream [lindex $match 0]
+            if {[lsearch -exact $streamnames $stream] == -1} {
+                lappend streamnames $stream
+            }
+        }
+        if {$provider == "startgg"} {
+            .n.s.stream.name configure -values $streamnames
+        }
+        showmatches $provider
+    } else {
+        set settings(msg) $data
     }
 
-    .n.s.buttons.fetch configure -state normal
-    .n.s.buttons.clear configure -state normal
-    .n.s.token configure -state normal
-    .n.s.tournamentslug configure -state normal
+    $::provider_refresh($provider) configure -state normal
+}
+
+# Show matches of selected stream, or of the first stream if none is
+# selected yet. Providers without streams have all matches on the "" stream.
+proc showmatches {provider} {
+    upvar #0 $provider settings
+    set stream $settings(stream)
+    if {$stream == "" && [llength $::matches($provider)] > 0} {
+        set stream [lindex $::matches($provider) 0 0]
+    }
+    set ::matches_shown($provider) {}
+    set ::matches_labels($provider) {}
+    set nextfound 0
+    foreach match $::matches($provider) {
+        lassign $match matchstream _ roundtext state p1name _ _ p2name _ _ _
+        if {$matchstream != $stream} {
+            continue
+        }
+        # Entrants that aren't known yet come with empty names.
+        if {$p1name == ""} { set p1name TBD }
+        if {$p2name == ""} { set p2name TBD }
+        set label "$roundtext: $p1name vs $p2name"
+        # Mark what "Get Next Match" would load.
+        set ready [expr {$p1name != "TBD" && $p2name != "TBD"}]
+        if {$state == "inprogress"} {
+            append label " (in progress)"
+        } elseif {$ready && !$nextfound} {
+            append label " (next)"
+        }
+        if {$ready} {
+            set nextfound 1
+        }
+        lappend ::matches_shown($provider) $match
+        lappend ::matches_labels($provider) $label
+    }
+}
+
+proc loadmatch {provider} {
+    upvar #0 $provider settings
+    set i [$::provider_tabs($provider).queue.list curselection]
+    if {$i == ""} {
+        set settings(msg) "Please select a match first."
+        return
+    }
+    lassign [lindex $::matches_shown($provider) $i] \
+        _ matchid roundtext _ p1name p1country p1team p2name p2country p2team \
+        subtitle
+    if {$p1name == "" || $p2name == ""} {
+        set settings(msg) "$roundtext is still waiting on TBD players."
+        return
+    }
+    set resp [ipc "loadmatch" $provider $matchid]
+    if {[lindex $resp 0] == "err"} {
+        set settings(msg) [lindex $resp 1]
+        return
+    }
+    set ::scoreboard(subtitle) $subtitle
+    # Country is updated whenever player name is updated,
+    # so make sure we set countries last.
+    set ::scoreboard(p1name) $p1name
+    set ::scoreboard(p1score) 0
+    set ::scoreboard(p1team) $p1team
+    set ::scoreboard(p2name) $p2name
+    set ::scoreboard(p2score) 0
+    set ::scoreboard(p2team) $p2team
+    set ::scoreboard(p1country) $p1country
+    set ::scoreboard(p2country) $p2country
+    set settings(msg) "Loaded $roundtext: $p1name vs $p2name"
+    set ::provider $provider
+    .n select .n.m
+}
+
+# Reports the applied scores of the last loaded match, after confirmation.
+proc reportmatch {} {
+    set provider $::provider
+    set missing [providermissing $provider]
+    if {$missing != ""} {
+        set ::mainstatus $missing
+        return
+    }
+    if {[catch {ipc_call "previewreport"} summary]} {
+        set ::mainstatus $summary
+        return
+    }
+    set answer [tk_messageBox -type yesno -icon question \
+        -title "Report result" -message $summary]
+    if {$answer != "yes"} {
+        return
+    }
+    .n.m.buttons.report configure -state disabled
+    set ::mainstatus "Reporting..."
+    providerrequest $provider "reportmatch" [list reportmatch__resp $provider] \
+        $provider {*}[providerargs $provider]
+}
+
+proc reportmatch__resp {provider status data} {
+    .n.m.buttons.report configure -state normal
+    if {$status != "ok"} {
+        set ::mainstatus $data
+        return
+    }
+    set ::mainstatus [dict get $data msg]
+    # Winners have moved on, so show what's next.
+    if {$provider == "offline"} {
+        fetchmatches offline
+    }
 }
 
 #TODO: Show bracket on frontend for editing/validation
-proc getbracket {} {
-    if {$::startgg(token) == "" || $::startgg(phasegroupid) == ""} {
-        set ::startgg(msg) "Please enter token & phase group id first."
+proc fetchbracket {provider} {
+    upvar #0 $provider settings
+    set missing [providermissing $provider]
+    if {$missing == "" && $provider == "startgg" \
+        && $::startgg(phasegroupid) == ""} {
+        set missing "Please pick a phase group first."
+    }
+    if {$missing != ""} {
+        set settings(msg) $missing
+        return
+    }
+    setbusy $provider 1
+    set settings(msg) "Fetching..."
+    providerrequest $provider "fetchbracket" [list fetchbracket__resp $provider] \
+        $provider {*}[providerargs $provider]
+}
+proc fetchbracket__resp {provider status data} {
+    upvar #0 $provider settings
+    if {$status == "ok"} {
+        set settings(msg) [dict get $data msg]
+    } else {
+        set settings(msg) $data
+    }
+    setbusy $provider 0
+}
+
+# Writes today's finished sets from the history log to CSV and JSON.
+proc exportsets {} {
+    set resp [ipc "exportsets"]
+    set ::mainstatus [lindex $resp 1]
+}
+
+# Current GUI values, applied or not, in scoreboard_keys order.
+proc scoreboardvalues {} {
+    set values {}
+    foreach key $::scoreboard_keys {
+        lappend values $::scoreboard($key)
+    }
+    return $values
+}
+
+proc loadpresets {} {
+    set resp [ipc "getpresets"]
+    set ::preset_names {}
+    set ::preset_savedgroups {}
+    set ::preset_labels {}
+    foreach {name groups} $resp {
+        lappend ::preset_names $name
+        lappend ::preset_savedgroups $groups
+        lappend ::preset_labels "$name ([join $groups ", "])"
+    }
+}
+
+proc selectedpreset {} {
+    set idx [.n.p.list.box curselection]
+    if {$idx == ""} {
+        return -1
+    }
+    return $idx
+}
+
+# Fills name and field groups from the selected preset, so that it can be
+# easily overwritten.
+proc showpreset {} {
+    set idx [selectedpreset]
+    if {$idx == -1} {
         return
     }
-    .n.s.buttons.fetch configure -state disabled
-    .n.s.buttons.clear configure -state disabled
-    .n.s.token configure -state disabled
-    .n.s.tournamentslug configure -state disabled
-    set ::startgg(msg) "Fetching..."
-    ipc_write "fetchbracket" $::startgg(token) $::startgg(phasegroupid)
+    set ::presets(name) [lindex $::preset_names $idx]
+    set groups [lindex $::preset_savedgroups $idx]
+    foreach {group _} $::preset_grouplabels {
+        set ::preset_groups($group) [expr {[lsearch -exact $groups $group] != -1}]
+    }
+}
+
+proc savepreset {} {
+    set groups {}
+    foreach {group _} $::preset_grouplabels {
+        if {$::preset_groups($group)} {
+            lappend groups $group
+        }
+    }
+    set resp [ipc "savepreset" $::presets(name) $groups {*}[scoreboardvalues]]
+    set ::presets(msg) [lindex $resp 1]
+    if {[lindex $resp 0] == "ok"} {
+        loadpresets
+    }
 }
-proc getbracket__resp {} {
-    set resp [ipc_read]
-    set status [lindex $resp 0]
-    set msg [lindex $resp 1]
 
-    set ::startgg(msg) $msg
+# Puts the preset's fields in the Main tab as unapplied edits, so they can
+# be checked before going on stream.
+proc loadpreset {} {
+    set idx [selectedpreset]
+    if {$idx == -1} {
+        set ::presets(msg) "Please select a preset first."
+        return
+    }
+    set resp [ipc "loadpreset" [lindex $::preset_names $idx] {*}[scoreboardvalues]]
+    set ::presets(msg) [lindex $resp 1]
+    if {[lindex $resp 0] != "ok"} {
+        return
+    }
+    # Country comes after name in scoreboard_keys, so it's not overwritten by
+    # the player's country from players.csv.
+    foreach key $::scoreboard_keys value [lrange $resp 2 end] {
+        set ::scoreboard($key) $value
+    }
+    .n select .n.m
+}
 
-    .n.s.buttons.fetch configure -state normal
-    .n.s.buttons.clear configure -state normal
-    .n.s.token configure -state normal
-    .n.s.tournamentslug configure -state normal
+proc deletepreset {} {
+    set idx [selectedpreset]
+    if {$idx == -1} {
+        set ::presets(msg) "Please select a preset first."
+        return
+    }
+    set resp [ipc "deletepreset" [lindex $::preset_names $idx]]
+    set ::presets(msg) [lindex $resp 1]
+    if {[lindex $resp 0] == "ok"} {
+        loadpresets
+    }
 }
 
 proc discardscoreboard {} {
@@ -510,6 +1511,18 @@ proc discardscoreboard {} {
     set ::scoreboard(p2country) $::applied_scoreboard(p2country)
 }
 
+# Goes back to (or forward from) a previously applied scoreboard. Unlike
+# scoreboardchanged, this also throws away unapplied edits, since the point
+# is to get back to a known state.
+proc undoscoreboard {action} {
+    set resp [ipc $action]
+    if {[lindex $resp 0] != "ok"} {
+        set ::mainstatus [lindex $resp 1]
+        return
+    }
+    loadscoreboard
+}
+
 proc update_applied_scoreboard {} {
     foreach key [array names ::scoreboard] {
         set ::applied_scoreboard($key) $::scoreboard($key)
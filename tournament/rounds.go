@@ -0,0 +1,62 @@
+package tournament
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// RoundNames maps round names and set formats of any provider to what we
+// actually want to show on stream, e.g. "Winners Semi-Final" => "WSF",
+// "Losers Round 4" => "Losers Top 8", or "Bo5" => "FT3".
+// Anything not in the map is shown as-is.
+type RoundNames map[string]string
+
+// LoadRoundNames reads a 2-column csv file of start.gg names and their
+// replacements. If file does not exist, it returns an empty map.
+func LoadRoundNames(filepath string) (RoundNames, error) {
+	result := make(RoundNames)
+
+	f, err := os.Open(filepath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("load round names: %w", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = 2
+	records, err := reader.ReadAll()
+	if err != nil {
+		return result, fmt.Errorf("csv parse error for %s: %w", filepath, err)
+	}
+
+	for _, record := range records {
+		result[record[0]] = record[1]
+	}
+	return result, nil
+}
+
+func (r RoundNames) Get(name string) string {
+	if mapped, ok := r[name]; ok {
+		return mapped
+	}
+	return name
+}
+
+// Subtitle returns the mapped round name and format of a match,
+// e.g. "WSF - FT3". Mapping a name to an empty string hides it.
+func (r RoundNames) Subtitle(m Match) string {
+	var parts []string
+	for _, part := range []string{m.RoundText, m.Format()} {
+		if mapped := r.Get(part); mapped != "" {
+			parts = append(parts, mapped)
+		}
+	}
+	return strings.Join(parts, " - ")
+}
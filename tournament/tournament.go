@@ -0,0 +1,131 @@
+// Package tournament is what GORTS needs from a tournament site, so that the
+// GUI can import players, load matches, fetch brackets and report results
+// the same way whether an event runs on start.gg, Challonge or elsewhere.
+package tournament
+
+import (
+	"context"
+	"fmt"
+
+	"go.imnhan.com/gorts/bracket"
+	"go.imnhan.com/gorts/players"
+)
+
+type Provider interface {
+	// FetchPlayers imports every participant. Progress may be called any
+	// number of times, including zero, unless it is nil.
+	FetchPlayers(ctx context.Context, progress func(fetched, total int)) ([]players.Player, error)
+	// FetchMatches returns matches that can be loaded into the scoreboard,
+	// in the order they should be played.
+	FetchMatches(ctx context.Context) ([]Match, error)
+	// FetchMatch returns the current state of a single match, so that we
+	// can check it hasn't changed before reporting it.
+	FetchMatch(ctx context.Context, id string) (Match, error)
+	FetchBracket(ctx context.Context) (bracket.Bracket, error)
+	Report(ctx context.Context, result Result) error
+}
+
+// Match is a set as far as the scoreboard is concerned.
+type Match struct {
+	Id string
+	// Name of the stream it's queued on, if the provider has such a thing.
+	Stream    string
+	RoundText string
+	// Best of how many games. Zero if unknown.
+	TotalGames int
+	State      bracket.State
+	// Unknown (TBD) entrants are left empty.
+	EntrantIds [2]string
+	Entrants   [2]players.Entrant
+}
+
+// Ready reports whether both entrants of the match are known.
+func (m Match) Ready() bool {
+	return m.EntrantIds[0] != "" && m.EntrantIds[1] != ""
+}
+
+// Format returns e.g. "Bo5", or an empty string if unknown.
+func (m Match) Format() string {
+	if m.TotalGames <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("Bo%d", m.TotalGames)
+}
+
+// NextMatch returns the first match of stream whose entrants are both known,
+// skipping matches still waiting on previous rounds. Empty stream means the
+// stream of the first match.
+func NextMatch(matches []Match, stream string) (match Match, skipped int, err error) {
+	if stream == "" && len(matches) > 0 {
+		stream = matches[0].Stream
+	}
+	queue := "the queue"
+	if stream != "" {
+		queue = stream + "'s queue"
+	}
+
+	for _, m := range matches {
+		if m.Stream != stream {
+			continue
+		}
+		if m.Ready() {
+			return m, skipped, nil
+		}
+		skipped++
+	}
+	if skipped == 0 {
+		return Match{}, 0, fmt.Errorf("No match found in %s", queue)
+	}
+	return Match{}, skipped, fmt.Errorf(
+		"All %d matches in %s are still waiting on TBD players", skipped, queue,
+	)
+}
+
+// CheckUnchanged returns an error if the match has been reported or its
+// entrants have changed since it was loaded.
+func CheckUnchanged(loaded, current Match) error {
+	if current.State == bracket.Completed {
+		return fmt.Errorf("%s was already reported.", current.RoundText)
+	}
+	if current.EntrantIds != loaded.EntrantIds {
+		return fmt.Errorf(
+			"Match has changed since it was loaded (now %s vs %s). "+
+				"Please reload it.",
+			players.MemberNames.Name(current.Entrants[0]),
+			players.MemberNames.Name(current.Entrants[1]),
+		)
+	}
+	return nil
+}
+
+// Result is the outcome of a match according to the scoreboard, with
+// everything ordered by the match's entrants rather than by scoreboard side,
+// since players may have been swapped on stream.
+type Result struct {
+	Match Match
+	// As displayed on the scoreboard.
+	Names      [2]string
+	Scores     [2]int
+	Characters [2]string
+}
+
+// Winner returns the index of the winning entrant, or -1 if scores are tied.
+func (r Result) Winner() int {
+	switch {
+	case r.Scores[0] > r.Scores[1]:
+		return 0
+	case r.Scores[1] > r.Scores[0]:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func (r Result) Summary() string {
+	return fmt.Sprintf(
+		"Report %s?\n\n%s %d - %d %s\n\nWinner: %s",
+		r.Match.RoundText,
+		r.Names[0], r.Scores[0], r.Scores[1], r.Names[1],
+		r.Names[r.Winner()],
+	)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// How often to send an SSE comment so that idle connections aren't dropped
+// by proxies or OBS' browser source.
+const KeepAliveInterval = 30 * time.Second
+
+// How long clients get to send a request. There's no write timeout, since
+// SSE streams stay open for as long as the overlay is.
+const (
+	ReadHeaderTimeout = 5 * time.Second
+	ReadTimeout       = 10 * time.Second
+)
+
+// How long to wait for in-flight requests when shutting down.
+const ShutdownTimeout = 5 * time.Second
+
+type WebServer struct {
+	server *http.Server
+	// Cancelling this tells long-lived requests i.e. SSE streams to end,
+	// otherwise graceful shutdown would wait for them forever.
+	cancel context.CancelFunc
+}
+
+func startWebServer(
+	addr string, state *State, catalog *Catalog, token string,
+) *WebServer {
+	ctx, cancel := context.WithCancel(context.Background())
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           webHandler(state, catalog, token),
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+		ReadHeaderTimeout: ReadHeaderTimeout,
+		ReadTimeout:       ReadTimeout,
+	}
+
+	// Listen first, so that we can tell where we're actually listening.
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	bound := ln.Addr().(*net.TCPAddr)
+	if bound.IP.IsUnspecified() {
+		fmt.Printf(
+			"Serving scoreboard on all network interfaces, port %d, "+
+				"e.g. http://localhost:%d\n", bound.Port, bound.Port,
+		)
+	} else {
+		println("Serving scoreboard at http://" + bound.String())
+	}
+	if !bound.IP.IsLoopback() && token == "" {
+		println("Anyone on the network can control the scoreboard: " +
+			"consider -token.")
+	}
+	go func() {
+		err := server.Serve(ln)
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	return &WebServer{server: server, cancel: cancel}
+}
+
+func (s *WebServer) Shutdown() {
+	s.cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		fmt.Printf("web server shutdown: %s\n", err)
+	}
+}
+
+func webHandler(state *State, catalog *Catalog, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir(WebDir)))
+	mux.HandleFunc("/state.json", handleState(state))
+	mux.HandleFunc("/fields.json", handleFields(state.Schema()))
+	mux.HandleFunc("/events", handleEvents(state))
+	mux.Handle("/api/", apiHandler(state, catalog, token))
+	return mux
+}
+
+// handleFields serves the custom fields, so that overlays know how to draw
+// them, e.g. country codes as flags.
+func handleFields(schema Schema) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if schema == nil {
+			schema = Schema{}
+		}
+		writeJSON(w, http.StatusOK, schema)
+	}
+}
+
+// handleState serves the scoreboard from memory so that polling overlays
+// don't hit the disk on every request.
+func handleState(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, state.Scoreboard())
+	}
+}
+
+// handleEvents streams the scoreboard as server-sent events: once on
+// connect, then again every time it's applied.
+func handleEvents(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+
+		updates, unsubscribe := state.Subscribe()
+		defer unsubscribe()
+
+		keepAlive := time.NewTicker(KeepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+
+			case scoreboard := <-updates:
+				blob, err := json.Marshal(scoreboard)
+				if err != nil {
+					fmt.Printf("Error: %s\n", err)
+					continue
+				}
+				fmt.Fprintf(w, "event: scoreboard\ndata: %s\n\n", blob)
+				flusher.Flush()
+
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keepalive\n\n")
+				flusher.Flush()
+			}
+		}
+	}
+}
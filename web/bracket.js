@@ -0,0 +1,137 @@
+const params = new URLSearchParams(window.location.search);
+// Only show rounds from this cut onwards e.g. top 8. 0 means show everything.
+const cut = parseInt(params.get("top")) || 0;
+
+// Returns [[round, [set, ...]], ...] sorted from earliest to latest round.
+const groupByRound = (sets) => {
+  const rounds = new Map();
+  sets.forEach((set) => {
+    if (!rounds.has(set.round)) {
+      rounds.set(set.round, []);
+    }
+    rounds.get(set.round).push(set);
+  });
+  return [...rounds.entries()].sort((a, b) => Math.abs(a[0]) - Math.abs(b[0]));
+};
+
+// In a top N cut, winners side has log2(N) - 1 rounds before grand final,
+// and losers side has twice that many. Single elimination has no losers side
+// so its final is part of the winners side.
+const cutRounds = (rounds, side, type) => {
+  if (cut <= 0) {
+    return rounds;
+  }
+  const winnersRounds = Math.log2(cut) - (type === "single_elimination" ? 0 : 1);
+  const keep = side === "losers" ? winnersRounds * 2 : winnersRounds;
+  return rounds.slice(Math.max(rounds.length - keep, 0));
+};
+
+const renderSlot = (slot) => {
+  const div = document.createElement("div");
+  div.classList.add("slot");
+  if (slot.winner) {
+    div.classList.add("winner");
+  }
+  if (!slot.entrant) {
+    div.classList.add("tbd");
+  }
+
+  const entrant = document.createElement("span");
+  entrant.classList.add("entrant");
+  entrant.textContent = slot.entrant || "TBD";
+
+  const score = document.createElement("span");
+  score.classList.add("score");
+  if (slot.score === null) {
+    score.textContent = "";
+  } else if (slot.score < 0) {
+    score.textContent = "DQ";
+  } else {
+    score.textContent = slot.score;
+  }
+
+  div.append(entrant, score);
+  return div;
+};
+
+const renderRound = (sets) => {
+  const div = document.createElement("div");
+  div.classList.add("round");
+
+  const title = document.createElement("div");
+  title.classList.add("round-title");
+  title.textContent = sets[0].roundtext;
+
+  const setsDiv = document.createElement("div");
+  setsDiv.classList.add("round-sets");
+  sets.forEach((set) => {
+    const setDiv = document.createElement("div");
+    setDiv.classList.add("set", set.state);
+    if (set.next) {
+      setDiv.classList.add("next");
+    }
+    set.slots.forEach((slot) => setDiv.append(renderSlot(slot)));
+    setsDiv.append(setDiv);
+  });
+
+  div.append(title, setsDiv);
+  return div;
+};
+
+const renderSide = (element, rounds) => {
+  element.replaceChildren(...rounds.map(([_, sets]) => renderRound(sets)));
+};
+
+const renderBracket = (bracket) => {
+  const winners = bracket.sets.filter((s) => s.side === "winners");
+  const losers = bracket.sets.filter((s) => s.side === "losers");
+  // Grand final reset is only shown once it's actually needed.
+  const grandFinals = bracket.sets.filter(
+    (s) =>
+      s.side === "grandfinal" &&
+      (!s.reset || s.state !== "pending" || s.slots.some((sl) => sl.entrant))
+  );
+
+  const winnersRounds = cutRounds(groupByRound(winners), "winners", bracket.type)
+    .concat(groupByRound(grandFinals));
+  const losersRounds = cutRounds(groupByRound(losers), "losers", bracket.type);
+
+  // Shrink text to fit the tallest column.
+  const tallest = Math.max(
+    ...winnersRounds.concat(losersRounds).map(([_, sets]) => sets.length)
+  );
+  const sides = losersRounds.length > 0 ? 2 : 1;
+  const fontSize = Math.min(24, Math.floor(900 / sides / tallest / 2 / 1.6));
+  document.body.style.setProperty("--slot-font-size", `${fontSize}px`);
+
+  renderSide(document.querySelector("#winners"), winnersRounds);
+  renderSide(document.querySelector("#losers"), losersRounds);
+};
+
+let lastBracket = "";
+const fetchHeaders = new Headers();
+fetchHeaders.append("pragma", "no-cache");
+fetchHeaders.append("cache-control", "no-cache");
+const pollBracket = () => {
+  fetch("bracket.json", { method: "GET", headers: fetchHeaders })
+    .then((response) => response.text())
+    .then((text) => {
+      if (text === lastBracket) {
+        return;
+      }
+      lastBracket = text;
+      const bracketDiv = document.querySelector("#bracket");
+      fadeIn(bracketDiv);
+      renderBracket(JSON.parse(text));
+    });
+};
+
+const fadeIn = (element) => {
+  element.classList.add("fade");
+  setTimeout(() => {
+    element.classList.remove("fade");
+  }, 1000);
+};
+
+pollBracket();
+setInterval(pollBracket, 5000);
@@ -30,6 +30,19 @@ function getFlagEmoji(countryCode) {
   return String.fromCodePoint(...codePoints);
 }
 
+// Built-in country fields, plus custom ones of type country, see
+// loadCountryFields.
+const countryFields = new Set(["p1country", "p2country"]);
+
+const loadCountryFields = () =>
+  fetch("fields.json", fetchInit)
+    .then((response) => response.json())
+    .then((fields) => {
+      fields
+        .filter((field) => field.type === "country")
+        .forEach((field) => countryFields.add(field.name));
+    });
+
 const drawDiffToDom = (diff) => {
   Object.keys(diff).forEach((key) => {
     const element = document.querySelector(`#${key}`);
@@ -46,7 +59,7 @@ const drawDiffToDom = (diff) => {
     };
 
     // Country needs to be converted from code to flag emoji
-    if (key === "p1country" || key === "p2country") {
+    if (countryFields.has(key)) {
       updateFunc = () => {
         element.innerHTML = getFlagEmoji(newValue);
       };
@@ -80,9 +93,42 @@ const pollState = () => {
     .then(applyNewState);
 };
 
+// Polling is only a fallback for when server-sent events aren't available,
+// e.g. the browser doesn't support them or the connection dropped.
+let pollInterval = null;
+const startPolling = () => {
+  if (pollInterval === null) {
+    pollState(); // immediately populate data to avoid empty values
+    pollInterval = setInterval(pollState, 1500);
+  }
+};
+const stopPolling = () => {
+  if (pollInterval !== null) {
+    clearInterval(pollInterval);
+    pollInterval = null;
+  }
+};
+
+// The server pushes the whole state once on connect, then again whenever
+// it's applied. EventSource reconnects by itself, so we only need to poll
+// while it's down.
+const listenState = () => {
+  if (!window.EventSource) {
+    startPolling();
+    return;
+  }
+  const source = new EventSource("events");
+  source.addEventListener("scoreboard", (event) => {
+    applyNewState(JSON.parse(event.data));
+  });
+  source.addEventListener("open", stopPolling);
+  source.addEventListener("error", startPolling);
+};
+
 /*
  * ACTUAL CODE FLOW STARTS HERE
  */
 window.STATE = {}; // state singleton, globally accessible
-pollState(); // immediately populate data to avoid empty values on page load
-setInterval(pollState, 1500);
+// Without custom fields, countries are still drawn as flags, so listen
+// regardless.
+loadCountryFields().catch(console.error).finally(listenState);